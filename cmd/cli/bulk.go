@@ -1,34 +1,55 @@
 package main
 
 import (
-	"bufio"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
-	"log"
+	"io"
 	"os"
-	"strings"
+	"strconv"
+	"time"
 
 	"github.com/multiplay/go-svrquery/lib/svrquery"
 	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
 )
 
+// Error codes of a BulkResponseError.
 const (
-	numWorkers = 100
-
-	// maxQueries is the maximum number of queries that can be queried in one bulk request.
-	maxQueries = 10000
+	errCodeUnsupportedProtocol = "unsupported_protocol"
+	errCodeInvalidEntry        = "invalid_entry"
+	errCodeTimeout             = "timeout"
+	errCodeUnreachable         = "unreachable"
+	errCodeAuth                = "auth"
+	errCodeMalformed           = "malformed"
+	errCodeUnsupportedVersion  = "unsupported_version"
+	errCodeChallenge           = "challenge"
+	errCodeQuery               = "query"
 )
 
 var (
-	errNoItem       = errors.New("no item")
-	errEntryInvalid = errors.New("invalid entry")
+	errUnsupportedProtocol = errors.New("unsupported protocol")
+
+	// basicColumns are the columns of a BulkResponseItem in tabular output.
+	basicColumns = []string{"address", "label", "currentPlayers", "maxPlayers", "map", "error"}
+
+	// fullColumns are the columns of a BulkFullResponseItem in tabular output.
+	fullColumns = []string{"address", "protocol", "label", "start", "latencyMs", "rttMs", "attempts", "currentPlayers", "maxPlayers", "map", "errorCode", "error"}
 )
 
+// bulkItem is the result of a query against a single server.
+type bulkItem interface {
+	// fields returns the values of the item's columns for tabular output.
+	fields() []string
+
+	// failed returns true if the query failed.
+	failed() bool
+}
+
 // BulkResponseItem contains the information about the query being performed
 // against a single server.
 type BulkResponseItem struct {
 	Address    string                      `json:"address"`
+	Label      string                      `json:"label,omitempty"`
 	ServerInfo *BulkResponseServerInfoItem `json:"serverInfo,omitempty"`
 	Error      string                      `json:"error,omitempty"`
 }
@@ -40,173 +61,278 @@ type BulkResponseServerInfoItem struct {
 	Map            string `json:"map"`
 }
 
-// BulkResponseItemWork is an item returned by a worker containing the data item
-// plus any terminal error it encountered.
-type BulkResponseItemWork struct {
-	Item *BulkResponseItem
-	Err  error
+// BulkFullResponseItem contains the complete response and timing of the query
+// performed against a single server.
+type BulkFullResponseItem struct {
+	Address    string                      `json:"address"`
+	Protocol   string                      `json:"protocol"`
+	Label      string                      `json:"label,omitempty"`
+	Start      *time.Time                  `json:"start,omitempty"`
+	LatencyMs  float64                     `json:"latencyMs"`
+	RTTMs      float64                     `json:"rttMs"`
+	Exchanges  []protocol.Exchange         `json:"exchanges,omitempty"`
+	Attempts   int                         `json:"attempts"`
+	ServerInfo *BulkResponseServerInfoItem `json:"serverInfo,omitempty"`
+	Response   protocol.Responser          `json:"response,omitempty"`
+	Error      *BulkResponseError          `json:"error,omitempty"`
+}
+
+// BulkResponseError is a query error classified by a machine-readable code.
+type BulkResponseError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
 
-// queryBulk queries a bulk set of servers using a query file.
-func queryBulk(file string) error {
-	// To simplify the workerpool load all the entries we are going to work on
-	lines := fileLines(file)
+// bulkOptions are the options for a bulk query.
+type bulkOptions struct {
+	// format is the format of the bulk file.
+	format string
 
-	if len(lines) > maxQueries {
-		return fmt.Errorf("too many servers requested %d (max %d)", len(lines), maxQueries)
-	}
+	// output is the format results are written in.
+	output string
 
-	// Make a jobs channel and a number of workers to processes
-	// work off of the channel.
-	jobChan := make(chan string, len(lines))
-	resultsChan := make(chan BulkResponseItemWork)
-	for w := 1; w <= numWorkers; w++ {
-		go worker(jobChan, resultsChan)
-	}
+	// workers is the number of servers queried concurrently.
+	workers int
+
+	// rate is the maximum number of queries started per second, no limit if zero.
+	rate float64
+
+	// ordered writes results in input order instead of as they complete.
+	ordered bool
+
+	// progress enables progress reporting to stderr.
+	progress bool
+
+	// full includes the complete response and timing in results.
+	full bool
 
-	items := make([]BulkResponseItem, 0, len(lines))
+	// ping pings servers instead of querying them, implying full.
+	ping bool
+}
 
-	// Queue work onto the channel
-	for _, line := range lines {
-		jobChan <- line
+// queryBulk queries a bulk set of servers using a query file, writing
+// the results to w as they complete.
+func queryBulk(w io.Writer, file string, opts bulkOptions) error {
+	entries, err := readBulkFile(file, opts.format)
+	if err != nil {
+		return fmt.Errorf("read bulk file: %w", err)
 	}
-	close(jobChan)
 
-	// Receive results from workers.
-	var err error
-	for i := 0; i < len(lines); i++ {
-		v := <-resultsChan
-		switch {
-		case errors.Is(v.Err, errNoItem):
-			// Not fatal, but no response for this entry was created.
-			continue
-		case v.Err != nil:
-			// We had a major issue processing the list
-			if err == nil {
-				err = fmt.Errorf("fatal error: %w", v.Err)
-				continue
-			}
-			err = fmt.Errorf("additional error: %w", v.Err)
-			continue
-		}
-		// add the item to our list of items.
-		items = append(items, *v.Item)
+	newItem, columns := bulkResponseItem, basicColumns
+	if opts.full || opts.ping {
+		newItem, columns = bulkFullResponseItem, fullColumns
 	}
 
+	bw, err := newBulkWriter(w, opts.output, columns)
 	if err != nil {
 		return err
 	}
 
-	b, err := json.MarshalIndent(items, "", "\t")
-	if err != nil {
-		return err
+	write := func(i int, item bulkItem) error {
+		return bw.Write(item)
+	}
+	if opts.ordered {
+		write = newOrderedWriter(bw).WriteIndex
 	}
-	fmt.Printf("%s\n", b)
-	return nil
-}
 
-func fileLines(file string) []string {
-	f, err := os.Open(file)
-	if err != nil {
-		log.Fatal(err)
+	var p *progress
+	if opts.progress {
+		p = &progress{w: os.Stderr, total: len(entries)}
 	}
-	defer f.Close()
 
-	result := make([]string, 0, 1000)
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		line := scanner.Text()
-		result = append(result, line)
+	targets := make([]svrquery.Target, 0, len(entries))
+	indices := make([]int, 0, len(entries))
+	for i, e := range entries {
+		err := e.err
+		if err == nil && !protocol.Supported(e.Protocol) {
+			err = fmt.Errorf("%w: %s", errUnsupportedProtocol, e.Protocol)
+		}
+
+		if err != nil {
+			// Not fatal, as we know which server it is for.
+			item := newItem(e, svrquery.Result{
+				Index:  i,
+				Target: e.target(),
+				Err:    err,
+			})
+			p.add(item)
+			if err = write(i, item); err != nil {
+				return err
+			}
+			continue
+		}
+
+		targets = append(targets, e.target())
+		indices = append(indices, i)
 	}
-	return result
-}
 
-// worker is run in a goroutine to provide processing for the items.
-func worker(jobChan <-chan string, results chan<- BulkResponseItemWork) {
-	for entry := range jobChan {
-		item, err := processBulkEntry(entry)
-		results <- BulkResponseItemWork{
-			Item: item,
-			Err:  err,
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	options := []svrquery.ManyOption{svrquery.WithConcurrency(opts.workers), svrquery.WithRate(opts.rate)}
+	if opts.ping {
+		options = append(options, svrquery.WithPing())
+	}
+	results := svrquery.QueryMany(ctx, targets, options...)
+	for r := range results {
+		i := indices[r.Index]
+		item := newItem(entries[i], r)
+		p.add(item)
+		if err = write(i, item); err != nil {
+			return err
 		}
 	}
+
+	return bw.Close()
 }
 
-// processBulkEntry processes an entry and returns an item containing the result or error.
-func processBulkEntry(entry string) (*BulkResponseItem, error) {
-	querySection, addressSection, err := parseEntry(entry)
-	if err != nil {
-		return nil, fmt.Errorf("parse file entry: %w", err)
+// bulkResponseItem returns the item for the result of a query.
+func bulkResponseItem(e bulkEntry, r svrquery.Result) bulkItem {
+	item := BulkResponseItem{
+		Address: e.Address,
+		Label:   e.Label,
 	}
 
-	item := &BulkResponseItem{
-		Address: addressSection,
+	switch {
+	case errors.Is(r.Err, errUnsupportedProtocol), errors.Is(r.Err, errEntryInvalid):
+		item.Error = r.Err.Error()
+	case r.Err != nil:
+		item.Error = fmt.Sprintf("query client: %s", r.Err)
+	default:
+		item.ServerInfo = serverInfo(r.Response)
 	}
+	return item
+}
 
-	// If the query contains any options retrieve them and
-	querySection, options, err := parseOptions(querySection)
-	if err != nil {
-		// These errors are non fatal, as we know which server it is for
-		item.Error = err.Error()
-		return item, nil
+// bulkFullResponseItem returns the item, including the complete response, for the result of a query.
+func bulkFullResponseItem(e bulkEntry, r svrquery.Result) bulkItem {
+	item := BulkFullResponseItem{
+		Address:   e.Address,
+		Protocol:  e.Protocol,
+		Label:     e.Label,
+		LatencyMs: milliseconds(r.Latency),
+		RTTMs:     milliseconds(rtt(r.Exchanges)),
+		Exchanges: r.Exchanges,
+		Attempts:  r.Attempts,
 	}
 
-	if !protocol.Supported(querySection) {
-		item.Error = fmt.Sprintf("unsupported protocol: %s", querySection)
-		return item, nil
+	if !r.Start.IsZero() {
+		item.Start = &r.Start
 	}
 
-	client, err := svrquery.NewClient(querySection, addressSection, options...)
-	if err != nil {
-		item.Error = fmt.Sprintf("create client: %s", err)
-		return item, nil
+	if r.Err != nil {
+		item.Error = &BulkResponseError{
+			Code:    errorCode(r.Err),
+			Message: r.Err.Error(),
+		}
+		return item
 	}
 
-	resp, err := client.Query()
-	if err != nil {
-		item.Error = fmt.Sprintf("query client: %s", err)
-		return item, nil
+	if r.Response != nil {
+		item.ServerInfo = serverInfo(r.Response)
+		item.Response = r.Response
+	}
+	return item
+}
+
+// rtt returns the round trip time of the first of exchanges which received a
+// response, normally the cheapest round trip of the protocol, zero if none did.
+func rtt(exchanges []protocol.Exchange) time.Duration {
+	for _, e := range exchanges {
+		if !e.Received.IsZero() {
+			return e.RTT()
+		}
 	}
+	return 0
+}
 
-	item.ServerInfo = &BulkResponseServerInfoItem{
+// milliseconds returns d as fractional milliseconds.
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// serverInfo returns the basic server information from resp.
+func serverInfo(resp protocol.Responser) *BulkResponseServerInfoItem {
+	si := &BulkResponseServerInfoItem{
 		CurrentPlayers: resp.NumClients(),
 		MaxPlayers:     resp.MaxClients(),
 		Map:            "UNKNOWN",
 	}
 
 	if currentMap, ok := resp.(protocol.Mapper); ok {
-		item.ServerInfo.Map = currentMap.Map()
+		si.Map = currentMap.Map()
 	}
-	return item, nil
+	return si
 }
 
-func parseEntry(entry string) (querySection, addressSection string, err error) {
-	entry = strings.TrimSpace(entry)
-	if entry == "" {
-		return "", "", fmt.Errorf("process entry: %w", errNoItem)
-	}
-	sections := strings.Split(entry, " ")
-	if len(sections) != 2 {
-		return "", "", fmt.Errorf("%w: wrong number of sections", errEntryInvalid)
+// errorCodes maps errors to their code, in order of precedence.
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{err: errUnsupportedProtocol, code: errCodeUnsupportedProtocol},
+	{err: errEntryInvalid, code: errCodeInvalidEntry},
+	{err: protocol.ErrTimeout, code: errCodeTimeout},
+	{err: protocol.ErrUnreachable, code: errCodeUnreachable},
+	{err: protocol.ErrAuth, code: errCodeAuth},
+	{err: protocol.ErrChallenge, code: errCodeChallenge},
+	{err: protocol.ErrUnsupportedVersion, code: errCodeUnsupportedVersion},
+	{err: protocol.ErrMalformed, code: errCodeMalformed},
+}
+
+// errorCode classifies err.
+func errorCode(err error) string {
+	for _, c := range errorCodes {
+		if errors.Is(err, c.err) {
+			return c.code
+		}
 	}
+	return errCodeQuery
+}
 
-	return sections[0], sections[1], nil
+// fields implements bulkItem.
+func (i BulkResponseItem) fields() []string {
+	fields := []string{i.Address, i.Label, "", "", "", i.Error}
+	if i.ServerInfo != nil {
+		fields[2] = strconv.FormatInt(i.ServerInfo.CurrentPlayers, 10)
+		fields[3] = strconv.FormatInt(i.ServerInfo.MaxPlayers, 10)
+		fields[4] = i.ServerInfo.Map
+	}
+	return fields
 }
 
-func parseOptions(querySection string) (baseQuery string, options []svrquery.Option, error error) {
-	options = make([]svrquery.Option, 0)
-	protocolSections := strings.Split(querySection, ",")
-	for i := 1; i < len(protocolSections); i++ {
-		keyVal := strings.SplitN(protocolSections[i], "=", 2)
-		if len(keyVal) != 2 {
-			return "", nil, fmt.Errorf("key value pair invalid: %v", keyVal)
+// failed implements bulkItem.
+func (i BulkResponseItem) failed() bool {
+	return i.Error != ""
+}
 
-		}
-		// Only support key at the moment.
-		switch strings.ToLower(keyVal[0]) {
-		case "key":
-			options = append(options, svrquery.WithKey(keyVal[1]))
-		}
+// fields implements bulkItem.
+func (i BulkFullResponseItem) fields() []string {
+	fields := []string{
+		i.Address,
+		i.Protocol,
+		i.Label,
+		"",
+		strconv.FormatFloat(i.LatencyMs, 'f', 3, 64),
+		strconv.FormatFloat(i.RTTMs, 'f', 3, 64),
+		strconv.Itoa(i.Attempts),
+		"", "", "", "", "",
+	}
+	if i.Start != nil {
+		fields[3] = i.Start.Format(time.RFC3339Nano)
+	}
+	if i.ServerInfo != nil {
+		fields[7] = strconv.FormatInt(i.ServerInfo.CurrentPlayers, 10)
+		fields[8] = strconv.FormatInt(i.ServerInfo.MaxPlayers, 10)
+		fields[9] = i.ServerInfo.Map
 	}
-	return protocolSections[0], options, nil
+	if i.Error != nil {
+		fields[10] = i.Error.Code
+		fields[11] = i.Error.Message
+	}
+	return fields
+}
+
+// failed implements bulkItem.
+func (i BulkFullResponseItem) failed() bool {
+	return i.Error != nil
 }
@@ -1,9 +1,22 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/multiplay/go-svrquery/lib/svrquery"
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol/sqp"
+	"github.com/multiplay/go-svrquery/lib/svrsample"
+	"github.com/multiplay/go-svrquery/lib/svrsample/common"
 	"github.com/stretchr/testify/require"
 )
 
@@ -80,15 +93,16 @@ func TestCreateClient(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			baseQuery, options, err := parseOptions(tc.query)
+			e, err := parseOptions(tc.query)
 			if err != nil {
 				require.ErrorIs(t, err, tc.expErr)
 				return
 			}
 			require.NoError(t, err)
-			require.Equal(t, tc.expQuery, baseQuery)
+			require.Equal(t, tc.expQuery, e.Protocol)
 
 			// Validate key setting
+			options := e.target().Options
 			if tc.expKey != "" {
 				require.Len(t, options, 1)
 				c := svrquery.Client{}
@@ -99,3 +113,146 @@ func TestCreateClient(t *testing.T) {
 		})
 	}
 }
+
+// sqpServer starts a sample sqp server returning its address.
+func sqpServer(t *testing.T) string {
+	t.Helper()
+	r, err := svrsample.GetResponder("sqp", common.QueryState{CurrentPlayers: 1, MaxPlayers: 2, Map: "Map"})
+	require.NoError(t, err)
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		b := make([]byte, 16)
+		for {
+			n, addr, err := conn.ReadFrom(b)
+			if err != nil {
+				return
+			}
+			resp, err := r.Respond(addr.String(), b[:n])
+			if err != nil {
+				continue
+			}
+			conn.WriteTo(resp, addr) // nolint: errcheck
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func TestQueryBulkFull(t *testing.T) {
+	silent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer silent.Close()
+
+	file := filepath.Join(t.TempDir(), "servers.txt")
+	data := fmt.Sprintf("sqp,label=ok,retries=1 %s\nfoo 1.2.3.4:1\nsqp,timeout=10ms,retries=1 %s\nsqp,timeout=soon 1.2.3.4:2\n", sqpServer(t), silent.LocalAddr())
+	require.NoError(t, os.WriteFile(file, []byte(data), 0o600))
+
+	var buf bytes.Buffer
+	require.NoError(t, queryBulk(&buf, file, bulkOptions{
+		format:  formatAuto,
+		output:  outputNDJSON,
+		workers: 2,
+		ordered: true,
+		full:    true,
+	}))
+
+	type fullItem struct {
+		BulkFullResponseItem
+		Response map[string]interface{} `json:"response"`
+	}
+	var items []fullItem
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var item fullItem
+		require.NoError(t, dec.Decode(&item))
+		items = append(items, item)
+	}
+	require.Len(t, items, 4)
+
+	ok := items[0]
+	require.Equal(t, "sqp", ok.Protocol)
+	require.Equal(t, "ok", ok.Label)
+	require.Nil(t, ok.Error)
+	require.Equal(t, 1, ok.Attempts)
+	require.NotNil(t, ok.Start)
+	require.Positive(t, ok.LatencyMs)
+	require.Positive(t, ok.RTTMs)
+	require.Len(t, ok.Exchanges, 2)
+	require.Equal(t, "challenge", ok.Exchanges[0].Name)
+	require.Equal(t, &BulkResponseServerInfoItem{CurrentPlayers: 1, MaxPlayers: 2, Map: "Map"}, ok.ServerInfo)
+	require.Contains(t, ok.Response, "server_info")
+
+	unsupported := items[1]
+	require.Equal(t, "foo", unsupported.Protocol)
+	require.Equal(t, 0, unsupported.Attempts)
+	require.Nil(t, unsupported.Start)
+	require.Equal(t, &BulkResponseError{Code: errCodeUnsupportedProtocol, Message: "unsupported protocol: foo"}, unsupported.Error)
+
+	timeout := items[2]
+	require.Equal(t, 2, timeout.Attempts)
+	require.Nil(t, timeout.Response)
+	require.Equal(t, errCodeTimeout, timeout.Error.Code)
+
+	invalid := items[3]
+	require.Equal(t, "1.2.3.4:2", invalid.Address)
+	require.Equal(t, 0, invalid.Attempts)
+	require.Equal(t, &BulkResponseError{Code: errCodeInvalidEntry, Message: `invalid entry: invalid timeout "soon"`}, invalid.Error)
+}
+
+func TestQueryBulkPing(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "servers.txt")
+	require.NoError(t, os.WriteFile(file, []byte("sqp "+sqpServer(t)+"\n"), 0o600))
+
+	var buf bytes.Buffer
+	require.NoError(t, queryBulk(&buf, file, bulkOptions{
+		format:  formatAuto,
+		output:  outputNDJSON,
+		workers: 1,
+		ping:    true,
+	}))
+
+	var item BulkFullResponseItem
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &item))
+	require.Nil(t, item.Error)
+	require.Nil(t, item.ServerInfo)
+	require.Positive(t, item.RTTMs)
+	require.Len(t, item.Exchanges, 1)
+	require.Equal(t, "challenge", item.Exchanges[0].Name)
+}
+
+func TestErrorCode(t *testing.T) {
+	testCases := []struct {
+		err  error
+		code string
+	}{
+		{err: fmt.Errorf("%w: foo", errUnsupportedProtocol), code: errCodeUnsupportedProtocol},
+		{err: fmt.Errorf("%w: missing address", errEntryInvalid), code: errCodeInvalidEntry},
+		{err: fmt.Errorf("query read: %w", fmt.Errorf("%w: %w", protocol.ErrTimeout, os.ErrDeadlineExceeded)), code: errCodeTimeout},
+		{err: fmt.Errorf("%w: %w", protocol.ErrUnreachable, syscall.ECONNREFUSED), code: errCodeUnreachable},
+		{err: fmt.Errorf("%w: bad key", protocol.ErrAuth), code: errCodeAuth},
+		{err: fmt.Errorf("%w: %w", protocol.ErrChallenge, sqp.NewErrMalformedPacketf("bad")), code: errCodeChallenge},
+		{err: fmt.Errorf("%w: 2", protocol.ErrUnsupportedVersion), code: errCodeUnsupportedVersion},
+		{err: sqp.NewErrMalformedPacketf("bad"), code: errCodeMalformed},
+		{err: errors.New("other"), code: errCodeQuery},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.code, func(t *testing.T) {
+			require.Equal(t, tc.code, errorCode(tc.err))
+		})
+	}
+}
+
+func TestRTT(t *testing.T) {
+	sent := time.Now()
+	require.Zero(t, rtt(nil))
+	require.Zero(t, rtt([]protocol.Exchange{{Sent: sent, TimedOut: true}}))
+	require.Equal(t, time.Millisecond*5, rtt([]protocol.Exchange{
+		{Sent: sent, TimedOut: true},
+		{Sent: sent, Received: sent.Add(time.Millisecond * 5)},
+	}))
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/multiplay/go-svrquery/lib/svrquery/discovery/valvemaster"
+)
+
+// discover writes the addresses of servers registered with the master server
+// at addr to w, in the bulk file format using proto as the query protocol.
+func discover(w io.Writer, addr, proto string, region int, filter string) error {
+	if region < 0 || region > 0xFF {
+		return fmt.Errorf("invalid region %d", region)
+	}
+
+	c, err := valvemaster.NewClient(addr)
+	if err != nil {
+		return err
+	}
+
+	f, err := parseFilter(filter)
+	if err != nil {
+		return err
+	}
+
+	// Stop discovery if we return early due to an error.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for r := range c.Servers(ctx, valvemaster.Region(region), f) {
+		if r.Err != nil {
+			return r.Err
+		}
+		if _, err = fmt.Fprintf(w, "%s %s\n", proto, r.Address); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseFilter parses a filter in the form \key\value\key\value.
+func parseFilter(filter string) (valvemaster.Filter, error) {
+	if filter == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(strings.TrimPrefix(filter, `\`), `\`)
+	if len(parts)%2 != 0 {
+		return nil, fmt.Errorf("invalid filter %q", filter)
+	}
+
+	f := make(valvemaster.Filter, 0, len(parts)/2)
+	for i := 0; i < len(parts); i += 2 {
+		f = append(f, [2]string{parts[i], parts[i+1]})
+	}
+	return f, nil
+}
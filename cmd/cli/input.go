@@ -0,0 +1,375 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/multiplay/go-svrquery/lib/svrquery"
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol/sqp"
+)
+
+// Bulk file formats.
+const (
+	formatAuto   = "auto"
+	formatText   = "text"
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+	formatCSV    = "csv"
+)
+
+var (
+	errNoItem       = errors.New("no item")
+	errEntryInvalid = errors.New("invalid entry")
+)
+
+// bulkEntry is a server to query read from a bulk file.
+type bulkEntry struct {
+	Protocol string `json:"protocol"`
+	Address  string `json:"address"`
+	Key      string `json:"key,omitempty"`
+	Timeout  string `json:"timeout,omitempty"`
+	Chunks   string `json:"chunks,omitempty"`
+	Retries  int    `json:"retries,omitempty"`
+	Label    string `json:"label,omitempty"`
+
+	timeout time.Duration
+
+	// err is set if the entry is invalid but its server is known, in which
+	// case it's reported as the result of the entry.
+	err error
+}
+
+// set sets the field identified by key to value, unknown keys are ignored.
+func (e *bulkEntry) set(key, value string) error {
+	switch strings.ToLower(strings.TrimSpace(key)) {
+	case "protocol":
+		e.Protocol = value
+	case "address":
+		e.Address = value
+	case "key":
+		e.Key = value
+	case "timeout":
+		e.Timeout = value
+	case "chunks":
+		e.Chunks = value
+	case "retries":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%w: invalid retries %q", errEntryInvalid, value)
+		}
+		e.Retries = n
+	case "label":
+		e.Label = value
+	}
+	return nil
+}
+
+// validate checks the entry is complete and its options are valid.
+func (e *bulkEntry) validate() error {
+	switch {
+	case e.Protocol == "":
+		return fmt.Errorf("%w: missing protocol", errEntryInvalid)
+	case e.Address == "":
+		return fmt.Errorf("%w: missing address", errEntryInvalid)
+	case e.Retries < 0:
+		return fmt.Errorf("%w: invalid retries %d", errEntryInvalid, e.Retries)
+	}
+
+	if e.Timeout != "" {
+		d, err := time.ParseDuration(e.Timeout)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("%w: invalid timeout %q", errEntryInvalid, e.Timeout)
+		}
+		e.timeout = d
+	}
+	return nil
+}
+
+// decoded returns the error of the entry after decoding it from JSON with err.
+// Values of the wrong type make the entry invalid, other errors are returned
+// unchanged as the JSON is invalid.
+func (e *bulkEntry) decoded(err error) error {
+	var te *json.UnmarshalTypeError
+	switch {
+	case err == nil:
+		return e.validate()
+	case errors.As(err, &te):
+		return fmt.Errorf("%w: invalid %s: %w", errEntryInvalid, te.Field, err)
+	}
+	return err
+}
+
+// invalid records err, from decoding or validating the entry, on the entry
+// if its address is known so it's reported as the result of the entry,
+// otherwise err is returned.
+func (e *bulkEntry) invalid(err error) error {
+	if err == nil || e.Address == "" {
+		return err
+	}
+	e.err = err
+	return nil
+}
+
+// target returns the target to query for the entry.
+func (e *bulkEntry) target() svrquery.Target {
+	options := make([]svrquery.Option, 0)
+	if e.Key != "" {
+		options = append(options, svrquery.WithKey(e.Key))
+	}
+	if e.timeout > 0 {
+		options = append(options, svrquery.WithTimeout(e.timeout))
+	}
+	if e.Chunks != "" {
+		options = append(options, svrquery.WithArg(sqp.ChunksArg, e.Chunks))
+	}
+
+	return svrquery.Target{
+		Protocol: e.Protocol,
+		Address:  e.Address,
+		Options:  options,
+		Retries:  e.Retries,
+	}
+}
+
+// readBulkFile reads the entries from file, which is read from stdin if "-".
+func readBulkFile(file, format string) ([]bulkEntry, error) {
+	r := os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	br := bufio.NewReader(r)
+	if format == formatAuto {
+		format = detectFormat(file, br)
+	}
+
+	return readBulk(br, format)
+}
+
+// detectFormat determines the format of a bulk file from its extension or content.
+func detectFormat(file string, br *bufio.Reader) string {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".json":
+		return formatJSON
+	case ".ndjson", ".jsonl":
+		return formatNDJSON
+	case ".csv":
+		return formatCSV
+	}
+
+	for i := 1; ; i++ {
+		b, err := br.Peek(i)
+		if err != nil {
+			return formatText
+		}
+
+		switch b[i-1] {
+		case ' ', '\t', '\r', '\n':
+			continue
+		case '[':
+			return formatJSON
+		case '{':
+			return formatNDJSON
+		}
+		return formatText
+	}
+}
+
+// readBulk reads the entries from r in format.
+func readBulk(r io.Reader, format string) ([]bulkEntry, error) {
+	switch format {
+	case formatText:
+		return readText(r)
+	case formatJSON:
+		return readJSON(r)
+	case formatNDJSON:
+		return readNDJSON(r)
+	case formatCSV:
+		return readCSV(r)
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+// readText reads entries in the form "proto[,option=value...] address",
+// one per line, with # comments.
+func readText(r io.Reader) ([]bulkEntry, error) {
+	var entries []bulkEntry
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		e, err := parseTextEntry(scanner.Text())
+		switch {
+		case errors.Is(err, errNoItem):
+			continue
+		case err != nil:
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// parseTextEntry parses a text entry. Invalid options are not fatal, as we
+// know which server they are for, so are recorded on the entry instead.
+func parseTextEntry(entry string) (bulkEntry, error) {
+	querySection, addressSection, err := parseEntry(entry)
+	if err != nil {
+		return bulkEntry{}, err
+	}
+
+	e, err := parseOptions(querySection)
+	e.Address = addressSection
+	if err == nil {
+		err = e.validate()
+	}
+	return e, e.invalid(err)
+}
+
+func parseEntry(entry string) (querySection, addressSection string, err error) {
+	if i := strings.IndexByte(entry, '#'); i >= 0 {
+		entry = entry[:i]
+	}
+
+	sections := strings.Fields(entry)
+	switch len(sections) {
+	case 0:
+		return "", "", fmt.Errorf("process entry: %w", errNoItem)
+	case 2:
+		return sections[0], sections[1], nil
+	}
+	return "", "", fmt.Errorf("%w: wrong number of sections", errEntryInvalid)
+}
+
+func parseOptions(querySection string) (bulkEntry, error) {
+	protocolSections := strings.Split(querySection, ",")
+	e := bulkEntry{Protocol: protocolSections[0]}
+	for i := 1; i < len(protocolSections); i++ {
+		keyVal := strings.SplitN(protocolSections[i], "=", 2)
+		if len(keyVal) != 2 {
+			return e, fmt.Errorf("%w: key value pair invalid: %v", errEntryInvalid, keyVal)
+		}
+
+		if err := e.set(keyVal[0], keyVal[1]); err != nil {
+			return e, err
+		}
+	}
+	return e, nil
+}
+
+// readJSON reads entries from a JSON array of objects.
+func readJSON(r io.Reader) ([]bulkEntry, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// line returns the line of the first value at or after offset.
+	line := func(offset int64) int {
+		i := int(offset)
+		for i < len(b) && strings.IndexByte(" \t\r\n,", b[i]) >= 0 {
+			i++
+		}
+		return bytes.Count(b[:i], []byte{'\n'}) + 1
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if t, err := dec.Token(); err != nil {
+		return nil, fmt.Errorf("line %d: %w", line(dec.InputOffset()), err)
+	} else if t != json.Delim('[') {
+		return nil, fmt.Errorf("line 1: %w: expected array", errEntryInvalid)
+	}
+
+	var entries []bulkEntry
+	for dec.More() {
+		n := line(dec.InputOffset())
+		var e bulkEntry
+		if err := e.invalid(e.decoded(dec.Decode(&e))); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		entries = append(entries, e)
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return nil, fmt.Errorf("line %d: %w", line(dec.InputOffset()), err)
+	}
+	return entries, nil
+}
+
+// readNDJSON reads entries from newline delimited JSON objects, with # comments.
+func readNDJSON(r io.Reader) ([]bulkEntry, error) {
+	var entries []bulkEntry
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		var e bulkEntry
+		if err := e.invalid(e.decoded(json.Unmarshal([]byte(text), &e))); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// readCSV reads entries from CSV with a header row naming the columns,
+// with # comments. Unknown columns are ignored.
+func readCSV(r io.Reader) ([]bulkEntry, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var entries []bulkEntry
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return entries, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		line, _ := cr.FieldPos(0)
+		var e bulkEntry
+		var entryErr error
+		for i, v := range record {
+			if v = strings.TrimSpace(v); v == "" {
+				continue
+			}
+			// Set the remaining fields after an error so the address is known.
+			if err = e.set(header[i], v); err != nil && entryErr == nil {
+				entryErr = err
+			}
+		}
+
+		if entryErr == nil {
+			entryErr = e.validate()
+		}
+		if err = e.invalid(entryErr); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		entries = append(entries, e)
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadBulk(t *testing.T) {
+	expected := []bulkEntry{
+		{Protocol: "sqp", Address: "1.2.3.4:1234"},
+		{
+			Protocol: "tf2e",
+			Address:  "1.2.3.4:1235",
+			Key:      "val",
+			Timeout:  "2s",
+			Chunks:   "info+metrics",
+			Retries:  2,
+			Label:    "eu-1",
+			timeout:  time.Second * 2,
+		},
+	}
+
+	testCases := []struct {
+		name   string
+		format string
+		input  string
+	}{
+		{
+			name:   "text",
+			format: formatText,
+			input: `# servers
+sqp 1.2.3.4:1234
+	tf2e,key=val,timeout=2s,chunks=info+metrics,retries=2,label=eu-1    1.2.3.4:1235 # trailing comment
+
+`,
+		},
+		{
+			name:   "json",
+			format: formatJSON,
+			input: `[
+	{"protocol": "sqp", "address": "1.2.3.4:1234"},
+	{"protocol": "tf2e", "address": "1.2.3.4:1235", "key": "val", "timeout": "2s", "chunks": "info+metrics", "retries": 2, "label": "eu-1", "other": 1}
+]`,
+		},
+		{
+			name:   "ndjson",
+			format: formatNDJSON,
+			input: `{"protocol": "sqp", "address": "1.2.3.4:1234"}
+# comment
+
+{"protocol": "tf2e", "address": "1.2.3.4:1235", "key": "val", "timeout": "2s", "chunks": "info+metrics", "retries": 2, "label": "eu-1"}
+`,
+		},
+		{
+			name:   "csv",
+			format: formatCSV,
+			input: `protocol, address, key, timeout, chunks, retries, label, owner
+# comment
+sqp, 1.2.3.4:1234,,,,,,ops
+tf2e, 1.2.3.4:1235, val, 2s, info+metrics, 2, eu-1, ops
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			entries, err := readBulk(strings.NewReader(tc.input), tc.format)
+			require.NoError(t, err)
+			require.Equal(t, expected, entries)
+
+			// Auto detection of the format from content.
+			if tc.format != formatCSV {
+				require.Equal(t, tc.format, detectFormat("-", bufio.NewReader(strings.NewReader(tc.input))))
+			}
+		})
+	}
+}
+
+func TestReadBulkErrors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		format string
+		input  string
+		expErr string
+	}{
+		{
+			name:   "text_sections",
+			format: formatText,
+			input:  "sqp 1.2.3.4:1234\n\nsqp 1.2.3.4:1234 extra\n",
+			expErr: "line 3: invalid entry: wrong number of sections",
+		},
+		{
+			name:   "json_address",
+			format: formatJSON,
+			input:  "[\n\t{\"protocol\": \"sqp\", \"address\": \"1.2.3.4:1234\"},\n\t{\"protocol\": \"sqp\"}\n]",
+			expErr: "line 3: invalid entry: missing address",
+		},
+		{
+			name:   "json_object",
+			format: formatJSON,
+			input:  `{"protocol": "sqp"}`,
+			expErr: "line 1: invalid entry: expected array",
+		},
+		{
+			name:   "json_syntax",
+			format: formatJSON,
+			input:  "[\n\t{\"protocol\": \"sqp\", \"address\": \"1.2.3.4:1234\"}\n\t{\"protocol\": \"sqp\"}\n]",
+			expErr: "line 3: invalid character '{' after array element",
+		},
+		{
+			name:   "ndjson_syntax",
+			format: formatNDJSON,
+			input:  "{\"protocol\": \"sqp\", \"address\": \"1.2.3.4:1234\"}\n{\"address\": \n",
+			expErr: "line 2: unexpected end of JSON input",
+		},
+		{
+			name:   "ndjson_address",
+			format: formatNDJSON,
+			input:  "{\"protocol\": \"sqp\", \"retries\": -1}\n",
+			expErr: "line 1: invalid entry: missing address",
+		},
+		{
+			name:   "csv_address",
+			format: formatCSV,
+			input:  "protocol,address,retries\nsqp,,1\n",
+			expErr: "line 2: invalid entry: missing address",
+		},
+		{
+			name:   "format",
+			format: "xml",
+			expErr: `unsupported format "xml"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := readBulk(strings.NewReader(tc.input), tc.format)
+			require.EqualError(t, err, tc.expErr)
+		})
+	}
+}
+
+func TestReadBulkInvalidEntries(t *testing.T) {
+	testCases := []struct {
+		name    string
+		format  string
+		input   string
+		expErrs []string
+	}{
+		{
+			name:   "text",
+			format: formatText,
+			input: `sqp,timeout=soon 1.2.3.4:1234
+sqp,label=eu-1,retries=many 1.2.3.4:1235
+sqp,key 1.2.3.4:1236
+sqp 1.2.3.4:1237
+`,
+			expErrs: []string{
+				`invalid entry: invalid timeout "soon"`,
+				`invalid entry: invalid retries "many"`,
+				`invalid entry: key value pair invalid: [key]`,
+			},
+		},
+		{
+			name:   "json",
+			format: formatJSON,
+			input: `[
+	{"protocol": "sqp", "address": "1.2.3.4:1234", "timeout": "soon"},
+	{"protocol": "sqp", "address": "1.2.3.4:1235", "label": "eu-1", "retries": "many"},
+	{"address": "1.2.3.4:1236"},
+	{"protocol": "sqp", "address": "1.2.3.4:1237"}
+]`,
+			expErrs: []string{
+				`invalid entry: invalid timeout "soon"`,
+				`invalid entry: invalid retries: json: cannot unmarshal string into Go struct field bulkEntry.retries of type int`,
+				`invalid entry: missing protocol`,
+			},
+		},
+		{
+			name:   "ndjson",
+			format: formatNDJSON,
+			input: `{"protocol": "sqp", "address": "1.2.3.4:1234", "timeout": "soon"}
+{"protocol": "sqp", "address": "1.2.3.4:1235", "label": "eu-1", "retries": -1}
+{"address": "1.2.3.4:1236"}
+{"protocol": "sqp", "address": "1.2.3.4:1237"}
+`,
+			expErrs: []string{
+				`invalid entry: invalid timeout "soon"`,
+				`invalid entry: invalid retries -1`,
+				`invalid entry: missing protocol`,
+			},
+		},
+		{
+			name:   "csv",
+			format: formatCSV,
+			input: `protocol,retries,address,label,timeout
+sqp,,1.2.3.4:1234,,soon
+sqp,many,1.2.3.4:1235,eu-1,
+,,1.2.3.4:1236,,
+sqp,,1.2.3.4:1237,,
+`,
+			expErrs: []string{
+				`invalid entry: invalid timeout "soon"`,
+				`invalid entry: invalid retries "many"`,
+				`invalid entry: missing protocol`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			entries, err := readBulk(strings.NewReader(tc.input), tc.format)
+			require.NoError(t, err)
+			require.Len(t, entries, 4)
+
+			for i, expErr := range tc.expErrs {
+				require.ErrorIs(t, entries[i].err, errEntryInvalid)
+				require.EqualError(t, entries[i].err, expErr)
+			}
+			require.Equal(t, "1.2.3.4:1235", entries[1].Address)
+			require.Equal(t, "eu-1", entries[1].Label)
+			require.NoError(t, entries[3].err)
+		})
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("sqp 1.2.3.4:1234"))
+	require.Equal(t, formatJSON, detectFormat("servers.json", r))
+	require.Equal(t, formatNDJSON, detectFormat("servers.jsonl", r))
+	require.Equal(t, formatCSV, detectFormat("servers.CSV", r))
+	require.Equal(t, formatText, detectFormat("servers.txt", r))
+	require.Equal(t, formatText, detectFormat("-", bufio.NewReader(strings.NewReader(""))))
+}
@@ -1,37 +1,137 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"os"
-	"time"
+	"os/signal"
+	"strings"
+	"syscall"
 
+	"github.com/multiplay/go-svrquery/lib/svrproxy"
 	"github.com/multiplay/go-svrquery/lib/svrquery"
+	"github.com/multiplay/go-svrquery/lib/svrquery/discovery/valvemaster"
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
 	"github.com/multiplay/go-svrquery/lib/svrsample"
 	"github.com/multiplay/go-svrquery/lib/svrsample/common"
 )
 
 func main() {
 	clientAddr := flag.String("addr", "", "Address to connect to e.g. 127.0.0.1:12345")
-	proto := flag.String("proto", "", "Protocol e.g. sqp, tf2e, tf2e-v7, tf2e-v8")
+	proto := flag.String("proto", "", "Protocol e.g. sqp, tf2e, tf2e-v7, tf2e-v8, comma separated to serve multiple protocols on one port")
 	key := flag.String("key", "", "Key to use to authenticate")
-	file := flag.String("file", "", "Bulk file to execute to get basic server information")
+	file := flag.String("file", "", "Bulk file to execute to get basic server information, - for stdin")
+	format := flag.String("format", formatAuto, "Bulk file format: auto, text, json, ndjson or csv")
+	output := flag.String("output", outputJSON, "Bulk output format: json, ndjson, csv or table")
+	workers := flag.Int("workers", svrquery.DefaultConcurrency, "Number of servers to query concurrently in bulk mode")
+	rate := flag.Float64("rate", 0, "Maximum number of queries per second in bulk mode, 0 for no limit")
+	ordered := flag.Bool("ordered", false, "Output bulk results in input order instead of as they complete")
+	showProgress := flag.Bool("progress", false, "Report bulk query progress on stderr")
+	full := flag.Bool("full", false, "Include timing in query results and the complete response, timing and classified errors in bulk results")
+	ping := flag.Bool("ping", false, "Measure round trip time using the cheapest request of the protocol instead of querying")
 	serverAddr := flag.String("server", "", "Address to start server e.g. 127.0.0.1:12121, :23232")
+	stateFile := flag.String("state", "", "JSON file containing the state of the server, which is reloaded when changed")
+	allow := flag.String("allow", "", "Comma separated CIDRs or IPs the server only responds to")
+	deny := flag.String("deny", "", "Comma separated CIDRs or IPs the server doesn't respond to")
+	limit := flag.Float64("limit", 0, "Maximum number of requests per second from each IP the server responds to, 0 for no limit (default 10 in proxy mode)")
+	burst := flag.Int("burst", 10, "Maximum burst of requests from each IP the server responds to when limited")
+	amplification := flag.Float64("amplification", svrsample.DefaultMaxAmplification, "Maximum server response size as a multiple of the request size for protocols without a challenge, 0 for no limit")
+	var faults svrsample.Faults
+	flag.DurationVar(&faults.Latency, "latency", 0, "Delay before the server sends each response")
+	flag.DurationVar(&faults.Jitter, "jitter", 0, "Maximum random delay added to the server latency")
+	flag.Float64Var(&faults.Drop, "drop", 0, "Probability between 0 and 1 the server drops a response")
+	flag.Float64Var(&faults.Duplicate, "duplicate", 0, "Probability between 0 and 1 the server sends a packet twice")
+	flag.Float64Var(&faults.Reorder, "reorder", 0, "Probability between 0 and 1 the server shuffles the packets of a response")
+	flag.Float64Var(&faults.WrongChallenge, "wrong-challenge", 0, "Probability between 0 and 1 the server responds with the wrong challenge")
+	flag.Float64Var(&faults.Truncate, "truncate", 0, "Probability between 0 and 1 the server truncates a packet")
+	flag.Float64Var(&faults.GarbageDataType, "garbage-type", 0, "Probability between 0 and 1 the server responds with an invalid data type")
+	flag.Float64Var(&faults.WrongPort, "wrong-port", 0, "Probability between 0 and 1 the server responds from a different port")
+	seed := flag.Int64("seed", 0, "Seed of the random server faults and simulated state, 0 for a random seed")
+	simulateCount := flag.Int("simulate", 0, "Number of servers to simulate on consecutive ports of the comma separated -server addresses using the comma separated -proto protocols, writing a bulk file to stdout")
+	proxyAddr := flag.String("proxy", "", "Address of a server to proxy, answering queries on the -server address from a cached snapshot")
+	proxyProto := flag.String("proxy-proto", "", "Protocol used to query the proxied server, by default the -proto used to answer queries")
+	interval := flag.Duration("interval", svrproxy.DefaultInterval, "Interval at which the proxied server is queried")
+	master := flag.String("master", "", "Valve master server to discover servers from, outputting a bulk file e.g. "+valvemaster.DefaultAddress)
+	region := flag.Int("region", int(valvemaster.RestOfWorld), "Region to discover servers in")
+	filter := flag.String("filter", "", `Filter for discovered servers e.g. \gamedir\rust\empty\1`)
 	flag.Parse()
 
 	l := log.New(os.Stderr, "", 0)
+	faults.Seed = *seed
+	serverOpts := serverOptions{
+		state:         *stateFile,
+		allow:         splitList(*allow),
+		deny:          splitList(*deny),
+		limit:         *limit,
+		burst:         *burst,
+		faults:        faults,
+		amplification: *amplification,
+	}
 
 	if *file != "" {
 		// Use bulk file mode
-		if err := queryBulk(*file); err != nil {
+		if err := queryBulk(os.Stdout, *file, bulkOptions{
+			format:   *format,
+			output:   *output,
+			workers:  *workers,
+			rate:     *rate,
+			ordered:  *ordered,
+			progress: *showProgress,
+			full:     *full,
+			ping:     *ping,
+		}); err != nil {
 			l.Fatal(err)
 		}
 		return
 	}
 
+	if *master != "" {
+		// Use discovery mode
+		if *proto == "" {
+			bail(l, "Protocol required in discovery mode")
+		}
+		if err := discover(os.Stdout, *master, *proto, *region, *filter); err != nil {
+			l.Fatal(err)
+		}
+		return
+	}
+
+	if *simulateCount > 0 {
+		// Use simulate mode
+		if *serverAddr == "" || *proto == "" {
+			bail(l, "Server address and protocol required in simulate mode")
+		}
+		simulateMode(l, simulateOptions{
+			count:    *simulateCount,
+			protos:   splitList(*proto),
+			addrs:    splitList(*serverAddr),
+			seed:     *seed,
+			interval: simulateInterval,
+			server:   serverOpts,
+		})
+		return
+	}
+
+	if *proxyAddr != "" {
+		// Use proxy mode
+		if *serverAddr == "" || *proto == "" {
+			bail(l, "Server address and protocol required in proxy mode")
+		}
+		proxyMode(l, *proto, *proxyAddr, *serverAddr, proxyOptions{
+			backendProto: *proxyProto,
+			rateLimit:    flagSet("limit"),
+			key:          *key,
+			interval:     *interval,
+			server:       serverOpts,
+		})
+		return
+	}
+
 	if *serverAddr != "" && *clientAddr != "" {
 		bail(l, "Cannot run both a server and a client. Specify either -addr OR -server flags")
 	}
@@ -41,24 +141,35 @@ func main() {
 		if *proto == "" {
 			bail(l, "No protocol provided in client mode")
 		}
-		serverMode(l, *proto, *serverAddr)
+		serverMode(l, *proto, *serverAddr, serverOpts)
 	case *clientAddr != "":
 		if *proto == "" {
 			bail(l, "Protocol required in server mode")
 		}
-		queryMode(l, *proto, *clientAddr, *key)
+		queryMode(l, *proto, *clientAddr, *key, *ping, *full)
 	default:
 		bail(l, "Please supply some options")
 	}
 }
 
-func queryMode(l *log.Logger, proto, address, key string) {
-	if err := query(proto, address, key); err != nil {
+func queryMode(l *log.Logger, proto, address, key string, ping, full bool) {
+	if err := query(os.Stdout, proto, address, key, ping, full); err != nil {
 		l.Fatal(err)
 	}
 }
 
-func query(proto, address, key string) error {
+// queryResult is the result of a query including its timing.
+type queryResult struct {
+	Address   string              `json:"address"`
+	Protocol  string              `json:"protocol"`
+	RTTMs     float64             `json:"rttMs"`
+	Exchanges []protocol.Exchange `json:"exchanges"`
+	Response  protocol.Responser  `json:"response,omitempty"`
+}
+
+// query queries or pings the server at address writing the result to w.
+// If ping or full are set the result includes its timing.
+func query(w io.Writer, proto, address, key string, ping, full bool) error {
 	options := make([]svrquery.Option, 0)
 	if key != "" {
 		options = append(options, svrquery.WithKey(key))
@@ -70,73 +181,175 @@ func query(proto, address, key string) error {
 	}
 	defer c.Close()
 
-	r, err := c.Query()
+	if !ping && !full {
+		r, err := c.Query()
+		if err != nil {
+			return err
+		}
+		return writeJSON(w, r)
+	}
+
+	qr := queryResult{Address: address, Protocol: proto}
+	if ping {
+		_, err = c.Ping()
+	} else {
+		qr.Response, err = c.Query()
+	}
 	if err != nil {
 		return err
 	}
 
-	b, err := json.MarshalIndent(r, "", "\t")
+	qr.Exchanges = c.Exchanges()
+	qr.RTTMs = milliseconds(rtt(qr.Exchanges))
+	return writeJSON(w, qr)
+}
+
+// writeJSON writes v to w as indented JSON.
+func writeJSON(w io.Writer, v interface{}) error {
+	b, err := json.MarshalIndent(v, "", "\t")
 	if err != nil {
 		return err
 	}
-	fmt.Printf("%s\n", b)
-	return nil
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
 }
 
-func serverMode(l *log.Logger, proto, serverAddr string) {
-	if err := server(l, proto, serverAddr); err != nil {
+func serverMode(l *log.Logger, proto, serverAddr string, opts serverOptions) {
+	if err := server(l, proto, serverAddr, opts); err != nil {
 		l.Fatal(err)
 	}
 }
 
-func server(l *log.Logger, proto, address string) error {
-	l.Printf("Starting sample server using protocol %s on %s", proto, address)
-	responder, err := svrsample.GetResponder(proto, common.QueryState{
-		CurrentPlayers: 1,
-		MaxPlayers:     2,
-		ServerName:     "Name",
-		GameType:       "Game Type",
-		Map:            "Map",
-		Port:           1000,
+// serverOptions are the options for the sample server.
+type serverOptions struct {
+	// state is the JSON file containing the state of the server, if any.
+	state string
+
+	// allow and deny are the CIDRs or IPs the server does and doesn't respond to.
+	allow []string
+	deny  []string
+
+	// limit is the maximum requests per second from each IP, no limit if zero.
+	limit float64
+
+	// burst is the maximum burst of requests from each IP when limited.
+	burst int
+
+	// amplification is the maximum response size as a multiple of the
+	// request size, no limit if zero.
+	amplification float64
+
+	// faults are the faults injected into responses.
+	faults svrsample.Faults
+}
+
+// flagSet returns true if the flag name was set on the command line.
+func flagSet(name string) bool {
+	var set bool
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
 	})
+	return set
+}
+
+// splitList returns the non-empty comma separated values of s.
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// options returns the svrsample.Server options, logging errors to l.
+func (o serverOptions) options(l *log.Logger) []svrsample.Option {
+	options := []svrsample.Option{
+		svrsample.WithErrorHandler(func(addr net.Addr, err error) {
+			l.Printf("error handling query from %s: %s", addr, err)
+		}),
+		svrsample.WithAllow(o.allow...),
+		svrsample.WithDeny(o.deny...),
+		svrsample.WithMaxAmplification(o.amplification),
+	}
+	if o.limit > 0 {
+		options = append(options, svrsample.WithRateLimit(o.limit, o.burst))
+	}
+	if o.injectsFaults() {
+		options = append(options, svrsample.WithFaults(o.faults))
+	}
+	return options
+}
+
+// injectsFaults returns true if faults are injected into responses.
+func (o serverOptions) injectsFaults() bool {
+	f := o.faults
+	f.Seed = 0
+	return f != (svrsample.Faults{})
+}
+
+func server(l *log.Logger, proto, address string, opts serverOptions) error {
+	l.Printf("Starting sample server using protocol %s on %s", proto, address)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	state := common.NewSyncState(defaultState)
+	if opts.state != "" {
+		f := &stateFile{name: opts.state}
+		s, _, err := f.load()
+		if err != nil {
+			return err
+		}
+		state.UpdateState(s)
+		go f.watch(ctx, l, state, stateInterval)
+	}
+
+	responder, err := newResponder(splitList(proto), state)
 	if err != nil {
 		return err
 	}
 
-	addr, err := net.ResolveUDPAddr("udp4", address)
+	if opts.injectsFaults() {
+		l.Printf("Injecting faults %+v", opts.faults)
+	}
+
+	s, err := svrsample.NewServer(address, responder, opts.options(l)...)
 	if err != nil {
 		return err
 	}
 
-	conn, err := net.ListenUDP("udp4", addr)
-	if err != nil {
+	if err = s.Serve(ctx); err != nil {
 		return err
 	}
 
-	for {
-		buf := make([]byte, 16)
-		_, to, err := conn.ReadFromUDP(buf)
-		if err != nil {
-			l.Println("read from udp", err)
-			continue
-		}
+	l.Printf("Stopped after %v", s.Stats())
+	return nil
+}
 
-		resp, err := responder.Respond(to.String(), buf)
-		if err != nil {
-			l.Println("error responding to query", err)
-			continue
-		}
+// newResponder returns the responder for protos using the state of provider.
+// Multiple protocols are served using a svrsample.Mux.
+func newResponder(protos []string, provider common.StateProvider) (common.QueryResponder, error) {
+	if len(protos) == 1 {
+		return svrsample.GetProviderResponder(protos[0], provider)
+	}
 
-		if err = conn.SetWriteDeadline(time.Now().Add(1 * time.Second)); err != nil {
-			l.Println("error setting write deadline")
-			continue
+	mux := svrsample.NewMux()
+	for _, proto := range protos {
+		m, err := svrsample.GetMatcher(proto)
+		if err != nil {
+			return nil, err
 		}
 
-		if _, err = conn.WriteTo(resp, to); err != nil {
-			l.Println("error writing response")
+		r, err := svrsample.GetProviderResponder(proto, provider)
+		if err != nil {
+			return nil, err
 		}
+		mux.Handle(m, r)
 	}
-
+	return mux, nil
 }
 
 func bail(l *log.Logger, msg string) {
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+// Bulk output formats.
+const (
+	outputJSON   = "json"
+	outputNDJSON = "ndjson"
+	outputCSV    = "csv"
+	outputTable  = "table"
+)
+
+// bulkWriter writes bulk response items as they are received.
+type bulkWriter interface {
+	Write(item bulkItem) error
+	Close() error
+}
+
+// newBulkWriter returns a bulkWriter which writes items to w in format,
+// using columns as the header for tabular formats.
+func newBulkWriter(w io.Writer, format string, columns []string) (bulkWriter, error) {
+	switch format {
+	case outputJSON:
+		return &jsonWriter{w: w}, nil
+	case outputNDJSON:
+		return &ndjsonWriter{enc: json.NewEncoder(w)}, nil
+	case outputCSV:
+		return &csvWriter{w: csv.NewWriter(w), columns: columns}, nil
+	case outputTable:
+		return &tableWriter{w: tabwriter.NewWriter(w, 0, 8, 2, ' ', 0), columns: columns}, nil
+	}
+	return nil, fmt.Errorf("unsupported output %q", format)
+}
+
+// jsonWriter writes items as an indented JSON array.
+type jsonWriter struct {
+	w io.Writer
+	n int
+}
+
+// Write implements bulkWriter.
+func (w *jsonWriter) Write(item bulkItem) error {
+	b, err := json.MarshalIndent(item, "\t", "\t")
+	if err != nil {
+		return err
+	}
+
+	sep := ",\n\t"
+	if w.n == 0 {
+		sep = "[\n\t"
+	}
+	w.n++
+	_, err = fmt.Fprintf(w.w, "%s%s", sep, b)
+	return err
+}
+
+// Close implements bulkWriter.
+func (w *jsonWriter) Close() error {
+	end := "\n]\n"
+	if w.n == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(w.w, end)
+	return err
+}
+
+// ndjsonWriter writes items as newline delimited JSON.
+type ndjsonWriter struct {
+	enc *json.Encoder
+}
+
+// Write implements bulkWriter.
+func (w *ndjsonWriter) Write(item bulkItem) error {
+	return w.enc.Encode(item)
+}
+
+// Close implements bulkWriter.
+func (w *ndjsonWriter) Close() error {
+	return nil
+}
+
+// csvWriter writes items as CSV with a header row.
+type csvWriter struct {
+	w       *csv.Writer
+	columns []string
+	header  bool
+}
+
+// Write implements bulkWriter.
+func (w *csvWriter) Write(item bulkItem) error {
+	if !w.header {
+		if err := w.w.Write(w.columns); err != nil {
+			return err
+		}
+		w.header = true
+	}
+
+	if err := w.w.Write(item.fields()); err != nil {
+		return err
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// Close implements bulkWriter.
+func (w *csvWriter) Close() error {
+	if !w.header {
+		if err := w.w.Write(w.columns); err != nil {
+			return err
+		}
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// tableWriter writes items as an aligned table. As the column widths depend
+// on every item, the table is only written on Close.
+type tableWriter struct {
+	w       *tabwriter.Writer
+	columns []string
+	header  bool
+}
+
+// Write implements bulkWriter.
+func (w *tableWriter) Write(item bulkItem) error {
+	if !w.header {
+		if err := w.writeRow(w.columns); err != nil {
+			return err
+		}
+		w.header = true
+	}
+	return w.writeRow(item.fields())
+}
+
+// writeRow writes a row of fields.
+func (w *tableWriter) writeRow(fields []string) error {
+	for i, f := range fields {
+		sep := "\t"
+		if i == len(fields)-1 {
+			sep = "\n"
+		}
+		if _, err := fmt.Fprintf(w.w, "%s%s", f, sep); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close implements bulkWriter.
+func (w *tableWriter) Close() error {
+	if !w.header {
+		if err := w.writeRow(w.columns); err != nil {
+			return err
+		}
+	}
+	return w.w.Flush()
+}
+
+// orderedWriter buffers items so they are written in input order.
+type orderedWriter struct {
+	bulkWriter
+	next    int
+	pending map[int]bulkItem
+}
+
+// newOrderedWriter returns a writer which writes items to w in index order.
+func newOrderedWriter(w bulkWriter) *orderedWriter {
+	return &orderedWriter{bulkWriter: w, pending: make(map[int]bulkItem)}
+}
+
+// WriteIndex writes item, which is at index i of the input, once all items
+// with a lower index have been written.
+func (w *orderedWriter) WriteIndex(i int, item bulkItem) error {
+	w.pending[i] = item
+	for {
+		item, ok := w.pending[w.next]
+		if !ok {
+			return nil
+		}
+
+		delete(w.pending, w.next)
+		w.next++
+		if err := w.Write(item); err != nil {
+			return err
+		}
+	}
+}
+
+// progress reports the progress of a bulk query.
+type progress struct {
+	w      io.Writer
+	total  int
+	done   int
+	errors int
+	last   time.Time
+}
+
+// add records the completion of an item, periodically reporting progress.
+func (p *progress) add(item bulkItem) {
+	if p == nil {
+		return
+	}
+
+	p.done++
+	if item.failed() {
+		p.errors++
+	}
+
+	if p.done < p.total && time.Since(p.last) < time.Millisecond*100 {
+		return
+	}
+	p.last = time.Now()
+	fmt.Fprintf(p.w, "\r%d/%d queried, %d errors", p.done, p.total, p.errors) // nolint: errcheck
+	if p.done == p.total {
+		fmt.Fprintln(p.w) // nolint: errcheck
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testItems = []bulkItem{
+	BulkResponseItem{
+		Address:    "1.2.3.4:1234",
+		Label:      "eu-1",
+		ServerInfo: &BulkResponseServerInfoItem{CurrentPlayers: 1, MaxPlayers: 2, Map: "Map"},
+	},
+	BulkResponseItem{
+		Address: "1.2.3.4:1235",
+		Error:   "unsupported protocol: foo",
+	},
+}
+
+func TestBulkWriter(t *testing.T) {
+	testCases := []struct {
+		output string
+		items  []bulkItem
+		exp    string
+	}{
+		{
+			output: outputJSON,
+			items:  testItems,
+			exp: `[
+	{
+		"address": "1.2.3.4:1234",
+		"label": "eu-1",
+		"serverInfo": {
+			"currentPlayers": 1,
+			"maxPlayers": 2,
+			"map": "Map"
+		}
+	},
+	{
+		"address": "1.2.3.4:1235",
+		"error": "unsupported protocol: foo"
+	}
+]
+`,
+		},
+		{
+			output: outputJSON,
+			exp:    "[]\n",
+		},
+		{
+			output: outputNDJSON,
+			items:  testItems,
+			exp: `{"address":"1.2.3.4:1234","label":"eu-1","serverInfo":{"currentPlayers":1,"maxPlayers":2,"map":"Map"}}
+{"address":"1.2.3.4:1235","error":"unsupported protocol: foo"}
+`,
+		},
+		{
+			output: outputCSV,
+			items:  testItems,
+			exp: `address,label,currentPlayers,maxPlayers,map,error
+1.2.3.4:1234,eu-1,1,2,Map,
+1.2.3.4:1235,,,,,unsupported protocol: foo
+`,
+		},
+		{
+			output: outputTable,
+			items:  testItems,
+			exp: "address       label  currentPlayers  maxPlayers  map  error\n" +
+				"1.2.3.4:1234  eu-1   1               2           Map  \n" +
+				"1.2.3.4:1235                                          unsupported protocol: foo\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.output, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := newBulkWriter(&buf, tc.output, basicColumns)
+			require.NoError(t, err)
+			for _, item := range tc.items {
+				require.NoError(t, w.Write(item))
+			}
+			require.NoError(t, w.Close())
+			require.Equal(t, tc.exp, buf.String())
+		})
+	}
+
+	_, err := newBulkWriter(&bytes.Buffer{}, "xml", basicColumns)
+	require.Error(t, err)
+}
+
+func TestOrderedWriter(t *testing.T) {
+	var buf bytes.Buffer
+	bw, err := newBulkWriter(&buf, outputNDJSON, basicColumns)
+	require.NoError(t, err)
+
+	w := newOrderedWriter(bw)
+	for _, i := range []int{2, 0, 3, 1} {
+		require.NoError(t, w.WriteIndex(i, BulkResponseItem{Address: string(rune('a' + i))}))
+		if i == 0 {
+			require.Equal(t, "{\"address\":\"a\"}\n", buf.String())
+		}
+	}
+	require.Equal(t, "{\"address\":\"a\"}\n{\"address\":\"b\"}\n{\"address\":\"c\"}\n{\"address\":\"d\"}\n", buf.String())
+}
+
+func TestQueryBulkOrdered(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "servers.txt")
+	require.NoError(t, os.WriteFile(file, []byte("foo 1.2.3.4:1\nsqp,timeout=10ms 127.0.0.1:1\nbar,label=l 1.2.3.4:3\n"), 0o600))
+
+	var buf bytes.Buffer
+	require.NoError(t, queryBulk(&buf, file, bulkOptions{
+		format:  formatAuto,
+		output:  outputNDJSON,
+		workers: 1,
+		ordered: true,
+	}))
+
+	dec := json.NewDecoder(&buf)
+	var addrs []string
+	for dec.More() {
+		var item BulkResponseItem
+		require.NoError(t, dec.Decode(&item))
+		require.NotEmpty(t, item.Error)
+		addrs = append(addrs, item.Address)
+	}
+	require.Equal(t, []string{"1.2.3.4:1", "127.0.0.1:1", "1.2.3.4:3"}, addrs)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/multiplay/go-svrquery/lib/svrproxy"
+	"github.com/multiplay/go-svrquery/lib/svrquery"
+)
+
+// proxyOptions are the options for proxying queries to a server.
+type proxyOptions struct {
+	// backendProto is the protocol used to query the backend, proto if empty.
+	backendProto string
+
+	// rateLimit is true if the server rate limit options were set, otherwise
+	// the proxy's default rate limit is used.
+	rateLimit bool
+
+	// key is the key used to query the backend.
+	key string
+
+	// interval is the interval at which the backend is queried.
+	interval time.Duration
+
+	// server are the options of the server answering queries.
+	server serverOptions
+}
+
+func proxyMode(l *log.Logger, proto, backend, addr string, opts proxyOptions) {
+	if err := proxy(l, proto, backend, addr, opts); err != nil {
+		l.Fatal(err)
+	}
+}
+
+// proxy answers queries on addr using proto with a snapshot of the backend
+// server, which is queried every interval, until interrupted.
+func proxy(l *log.Logger, proto, backend, addr string, opts proxyOptions) error {
+	backendProto := opts.backendProto
+	if backendProto == "" {
+		backendProto = proto
+	}
+
+	l.Printf("Starting proxy using protocol %s on %s for %s using protocol %s", proto, addr, backend, backendProto)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	options := []svrproxy.Option{
+		svrproxy.WithProtocol(proto),
+		svrproxy.WithInterval(opts.interval),
+		svrproxy.WithErrorHandler(func(err error) {
+			if errors.Is(err, svrproxy.ErrUnservable) {
+				l.Println("adjusted snapshot", err)
+				return
+			}
+			l.Println("error refreshing snapshot", err)
+		}),
+	}
+	if opts.key != "" {
+		options = append(options, svrproxy.WithClientOptions(svrquery.WithKey(opts.key)))
+	}
+	if opts.rateLimit {
+		options = append(options, svrproxy.WithRateLimit(opts.server.limit, opts.server.burst))
+	}
+
+	// The rate limit is applied by the proxy, which limits by default.
+	server := opts.server
+	server.limit = 0
+	options = append(options, svrproxy.WithServerOptions(server.options(l)...))
+
+	p, err := svrproxy.New(backendProto, backend, addr, options...)
+	if err != nil {
+		return err
+	}
+
+	if err = p.Serve(ctx); err != nil {
+		return err
+	}
+
+	l.Printf("Stopped after %v", p.Stats())
+	return nil
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/multiplay/go-svrquery/lib/svrsample"
+	"github.com/multiplay/go-svrquery/lib/svrsample/common"
+)
+
+// simulateInterval is the interval at which the state of simulated servers changes.
+const simulateInterval = time.Second
+
+var (
+	// simulatedMaps are the maps simulated servers rotate through.
+	simulatedMaps = []string{"dust", "harbour", "foundry", "canyon", "outpost", "glacier"}
+
+	// simulatedGameTypes are the game types of simulated servers.
+	simulatedGameTypes = []string{"Deathmatch", "Team Deathmatch", "Capture the Flag", "King of the Hill"}
+
+	// simulatedMaxPlayers are the max players of simulated servers.
+	simulatedMaxPlayers = []int32{8, 16, 32, 64}
+)
+
+// simulateOptions are the options for simulating a fleet of servers.
+type simulateOptions struct {
+	// count is the number of servers.
+	count int
+
+	// protos are the protocols of the servers, which are used in turn.
+	protos []string
+
+	// addrs are the base addresses of the servers, which are used in turn
+	// with consecutive ports.
+	addrs []string
+
+	// seed is the seed of the random state of the servers, if zero a random seed is used.
+	seed int64
+
+	// interval is the interval at which the state of the servers changes.
+	interval time.Duration
+
+	// server are the options of each server.
+	server serverOptions
+}
+
+// simulatedServer is a sample server with randomized, evolving state.
+type simulatedServer struct {
+	proto   string
+	server  *svrsample.Server
+	state   *common.SyncState
+	rnd     *rand.Rand
+	players int
+}
+
+func simulateMode(l *log.Logger, opts simulateOptions) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := simulate(ctx, os.Stdout, l, opts); err != nil {
+		l.Fatal(err)
+	}
+}
+
+// simulate starts the simulated servers, writing a bulk file to query them
+// to w, and serves them until ctx is done.
+func simulate(ctx context.Context, w io.Writer, l *log.Logger, opts simulateOptions) error {
+	if opts.count < 1 {
+		return fmt.Errorf("invalid server count %d", opts.count)
+	} else if len(opts.protos) == 0 {
+		return fmt.Errorf("no protocols")
+	}
+
+	addrs, err := simulatedAddrs(opts.addrs, opts.count)
+	if err != nil {
+		return err
+	}
+
+	if opts.seed == 0 {
+		opts.seed = time.Now().UnixNano()
+	}
+	l.Printf("Simulating %d servers with seed %d", opts.count, opts.seed)
+
+	servers := make([]*simulatedServer, 0, opts.count)
+	defer func() {
+		for _, s := range servers {
+			s.server.Shutdown(context.Background()) // nolint: errcheck
+		}
+	}()
+
+	for i, addr := range addrs {
+		s, err := newSimulatedServer(l, i, opts.protos[i%len(opts.protos)], addr, opts.seed+int64(i), opts.server)
+		if err != nil {
+			return fmt.Errorf("server %d: %w", i, err)
+		}
+		servers = append(servers, s)
+
+		if _, err = fmt.Fprintf(w, "%s,label=sim-%d %s\n", s.proto, i, s.server.Addr()); err != nil {
+			return err
+		}
+	}
+
+	for _, s := range servers {
+		go s.server.Serve(ctx) // nolint: errcheck
+	}
+
+	t := time.NewTicker(opts.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			var st svrsample.Stats
+			for _, s := range servers {
+				st.Add(s.server.Stats())
+			}
+			l.Printf("Stopped after %v", st)
+			return nil
+		case <-t.C:
+			for _, s := range servers {
+				s.tick()
+			}
+		}
+	}
+}
+
+// simulatedAddrs returns count addresses spread across the base addresses
+// addrs, each with consecutive ports. A base port of zero uses random ports.
+func simulatedAddrs(addrs []string, count int) ([]string, error) {
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no addresses")
+	}
+
+	hosts := make([]string, len(addrs))
+	ports := make([]int, len(addrs))
+	for i, addr := range addrs {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		hosts[i] = host
+		if ports[i], err = strconv.Atoi(port); err != nil {
+			return nil, fmt.Errorf("invalid port %q", port)
+		}
+	}
+
+	res := make([]string, count)
+	for i := range res {
+		j := i % len(addrs)
+		port := ports[j]
+		if port != 0 {
+			port += i / len(addrs)
+		}
+		if port > 0xFFFF {
+			return nil, fmt.Errorf("port range of %s exceeded by %d servers", addrs[j], count)
+		}
+		res[i] = net.JoinHostPort(hosts[j], strconv.Itoa(port))
+	}
+	return res, nil
+}
+
+// newSimulatedServer returns a listening simulated server with id using proto on addr.
+func newSimulatedServer(l *log.Logger, id int, proto, addr string, seed int64, opts serverOptions) (*simulatedServer, error) {
+	s := &simulatedServer{
+		proto: proto,
+		rnd:   rand.New(rand.NewSource(seed)), // nolint: gosec
+	}
+
+	state := common.QueryState{
+		MaxPlayers: simulatedMaxPlayers[s.rnd.Intn(len(simulatedMaxPlayers))],
+		ServerName: fmt.Sprintf("Simulated Server %d", id),
+		GameType:   simulatedGameTypes[s.rnd.Intn(len(simulatedGameTypes))],
+		BuildID:    "simulated",
+		Map:        simulatedMaps[s.rnd.Intn(len(simulatedMaps))],
+		Metrics:    []float32{float32(s.rnd.Intn(50)), 30 + float32(s.rnd.Intn(30))},
+	}
+	for n := s.rnd.Intn(int(state.MaxPlayers) + 1); n > 0; n-- {
+		s.join(&state)
+	}
+	s.state = common.NewSyncState(state)
+
+	responder, err := svrsample.GetProviderResponder(proto, s.state)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.faults.Seed != 0 {
+		// Faults differ between servers but remain repeatable.
+		opts.faults.Seed += int64(id)
+	}
+
+	if s.server, err = svrsample.NewServer(addr, responder, opts.options(l)...); err != nil {
+		return nil, err
+	} else if err = s.server.Listen(); err != nil {
+		return nil, err
+	}
+
+	s.state.Update(func(qs *common.QueryState) {
+		qs.Port = uint16(s.server.Addr().(*net.UDPAddr).Port)
+	})
+	return s, nil
+}
+
+// tick evolves the state of the server with players joining and leaving,
+// scores increasing, the map rotating and metrics drifting.
+func (s *simulatedServer) tick() {
+	s.state.Update(func(qs *common.QueryState) {
+		switch n := s.rnd.Intn(3); {
+		case n == 0 && qs.CurrentPlayers < qs.MaxPlayers:
+			s.join(qs)
+		case n == 1 && qs.CurrentPlayers > 0:
+			i := s.rnd.Intn(len(qs.Players))
+			qs.Players = append(qs.Players[:i], qs.Players[i+1:]...)
+			qs.CurrentPlayers--
+		}
+
+		for _, p := range qs.Players {
+			p["score"] = common.NewUint32(p["score"].Value.(uint32) + uint32(s.rnd.Intn(3)))
+		}
+
+		if s.rnd.Intn(60) == 0 {
+			for i, m := range simulatedMaps {
+				if m == qs.Map {
+					qs.Map = simulatedMaps[(i+1)%len(simulatedMaps)]
+					break
+				}
+			}
+			for _, p := range qs.Players {
+				p["score"] = common.NewUint32(0)
+			}
+		}
+
+		for i, m := range qs.Metrics {
+			if m += float32(s.rnd.NormFloat64()); m >= 0 {
+				qs.Metrics[i] = m
+			}
+		}
+	})
+}
+
+// join adds a new player to qs.
+func (s *simulatedServer) join(qs *common.QueryState) {
+	s.players++
+	qs.Players = append(qs.Players, common.Record{
+		"name":  common.NewString(fmt.Sprintf("player-%d", s.players)),
+		"score": common.NewUint32(0),
+	})
+	qs.CurrentPlayers++
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/multiplay/go-svrquery/lib/svrsample/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimulatedAddrs(t *testing.T) {
+	addrs, err := simulatedAddrs([]string{"127.0.0.1:20000", "127.0.0.2:20000"}, 5)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"127.0.0.1:20000",
+		"127.0.0.2:20000",
+		"127.0.0.1:20001",
+		"127.0.0.2:20001",
+		"127.0.0.1:20002",
+	}, addrs)
+
+	addrs, err = simulatedAddrs([]string{"127.0.0.1:0"}, 2)
+	require.NoError(t, err)
+	require.Equal(t, []string{"127.0.0.1:0", "127.0.0.1:0"}, addrs)
+
+	_, err = simulatedAddrs([]string{"127.0.0.1:65535"}, 2)
+	require.Error(t, err)
+
+	_, err = simulatedAddrs(nil, 1)
+	require.Error(t, err)
+}
+
+func TestSimulatedServerTick(t *testing.T) {
+	l := log.New(io.Discard, "", 0)
+	states := make([]common.QueryState, 2)
+	for i := range states {
+		s, err := newSimulatedServer(l, 1, "sqp", "127.0.0.1:0", 42, serverOptions{})
+		require.NoError(t, err)
+		defer s.server.Shutdown(context.Background()) // nolint: errcheck
+
+		for j := 0; j < 100; j++ {
+			s.tick()
+			st := s.state.State()
+			require.Len(t, st.Players, int(st.CurrentPlayers))
+			require.LessOrEqual(t, st.CurrentPlayers, st.MaxPlayers)
+		}
+		states[i] = s.state.State()
+		states[i].Port = 0
+	}
+
+	// The same seed results in the same state.
+	require.Equal(t, "Simulated Server 1", states[0].ServerName)
+	require.Equal(t, states[0], states[1])
+}
+
+func TestSimulate(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r, w := io.Pipe()
+	errc := make(chan error, 1)
+	go func() {
+		errc <- simulate(ctx, w, log.New(io.Discard, "", 0), simulateOptions{
+			count:    3,
+			protos:   []string{"sqp"},
+			addrs:    []string{"127.0.0.1:0"},
+			seed:     1,
+			interval: time.Millisecond * 10,
+		})
+	}()
+
+	var lines []string
+	sc := bufio.NewScanner(r)
+	for len(lines) < 3 && sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+	require.Len(t, lines, 3)
+	require.True(t, strings.HasPrefix(lines[2], "sqp,label=sim-2 127.0.0.1:"), lines[2])
+
+	file := filepath.Join(t.TempDir(), "servers.txt")
+	require.NoError(t, os.WriteFile(file, []byte(strings.Join(lines, "\n")), 0o600))
+
+	var buf bytes.Buffer
+	require.NoError(t, queryBulk(&buf, file, bulkOptions{
+		format:  formatAuto,
+		output:  outputNDJSON,
+		workers: 3,
+		ordered: true,
+	}))
+
+	var n int
+	dec := json.NewDecoder(&buf)
+	for ; dec.More(); n++ {
+		var item BulkResponseItem
+		require.NoError(t, dec.Decode(&item))
+		require.Empty(t, item.Error)
+		require.NotNil(t, item.ServerInfo)
+		require.Contains(t, simulatedMaps, item.ServerInfo.Map)
+	}
+	require.Equal(t, 3, n)
+
+	cancel()
+	require.NoError(t, <-errc)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/multiplay/go-svrquery/lib/svrsample/common"
+)
+
+// stateInterval is the interval at which a state file is checked for changes.
+const stateInterval = time.Second
+
+// defaultState is the state of the sample server if no state file is given.
+var defaultState = common.QueryState{
+	CurrentPlayers: 1,
+	MaxPlayers:     2,
+	ServerName:     "Name",
+	GameType:       "Game Type",
+	Map:            "Map",
+	Port:           1000,
+}
+
+// stateFile is a JSON file containing the state of the sample server.
+type stateFile struct {
+	name    string
+	modTime time.Time
+	size    int64
+}
+
+// load reads the state if the file has changed since it was last loaded,
+// otherwise false is returned.
+func (f *stateFile) load() (common.QueryState, bool, error) {
+	fi, err := os.Stat(f.name)
+	if err != nil {
+		return common.QueryState{}, false, err
+	} else if fi.ModTime().Equal(f.modTime) && fi.Size() == f.size {
+		return common.QueryState{}, false, nil
+	}
+
+	b, err := os.ReadFile(f.name)
+	if err != nil {
+		return common.QueryState{}, false, err
+	}
+
+	// Invalid content is only reported once per change.
+	f.modTime = fi.ModTime()
+	f.size = fi.Size()
+
+	var state common.QueryState
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err = dec.Decode(&state); err != nil {
+		return common.QueryState{}, false, fmt.Errorf("decode state %s: %w", f.name, err)
+	}
+	return state, true, nil
+}
+
+// watch checks the file every interval, updating state when it changes,
+// until ctx is done. Errors are logged and the previous state is kept.
+func (f *stateFile) watch(ctx context.Context, l *log.Logger, state *common.SyncState, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		s, changed, err := f.load()
+		if err != nil {
+			l.Println("error reloading state", err)
+			continue
+		} else if changed {
+			l.Printf("Reloaded state from %s", f.name)
+			state.UpdateState(s)
+		}
+	}
+}
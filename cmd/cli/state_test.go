@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/multiplay/go-svrquery/lib/svrsample/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStateFileLoad(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "state.json")
+	f := &stateFile{name: name}
+
+	_, _, err := f.load()
+	require.Error(t, err)
+
+	require.NoError(t, os.WriteFile(name, []byte(`{"current_players": 3, "max_players": 8, "map": "dust", "metrics": [1.5]}`), 0o600))
+	s, changed, err := f.load()
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.Equal(t, common.QueryState{CurrentPlayers: 3, MaxPlayers: 8, Map: "dust", Metrics: []float32{1.5}}, s)
+
+	_, changed, err = f.load()
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	require.NoError(t, os.WriteFile(name, []byte(`{"current_player": 3}`), 0o600))
+	_, _, err = f.load()
+	require.Error(t, err)
+}
+
+func TestStateFileWatch(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "state.json")
+	require.NoError(t, os.WriteFile(name, []byte(`{"current_players": 1}`), 0o600))
+
+	f := &stateFile{name: name}
+	s, _, err := f.load()
+	require.NoError(t, err)
+	state := common.NewSyncState(s)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var logs bytes.Buffer
+	go f.watch(ctx, log.New(&logs, "", 0), state, time.Millisecond*10)
+
+	// Invalid state is ignored.
+	require.NoError(t, os.WriteFile(name, []byte(`{`), 0o600))
+	time.Sleep(time.Millisecond * 50)
+	require.Equal(t, int32(1), state.State().CurrentPlayers)
+
+	require.NoError(t, os.WriteFile(name, []byte(`{"current_players": 12}`), 0o600))
+	require.Eventually(t, func() bool {
+		return state.State().CurrentPlayers == 12
+	}, time.Second, time.Millisecond*10)
+}
@@ -0,0 +1,313 @@
+// Package svrproxy provides a query proxy which sits in front of the query
+// port of a game server, answering queries from a cached snapshot of its
+// state, which protects the game process from query floods.
+package svrproxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/multiplay/go-svrquery/lib/svrquery"
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol/sqp"
+	"github.com/multiplay/go-svrquery/lib/svrsample"
+	"github.com/multiplay/go-svrquery/lib/svrsample/common"
+)
+
+const (
+	// DefaultInterval is the default interval at which the backend is queried.
+	DefaultInterval = 5 * time.Second
+
+	// DefaultMaxAge is the default maximum age of a snapshot which is used to
+	// answer queries, after which the backend is considered down.
+	DefaultMaxAge = 30 * time.Second
+
+	// DefaultRate is the default maximum number of requests per second from each IP.
+	DefaultRate = 10
+
+	// DefaultBurst is the default maximum burst of requests from each IP.
+	DefaultBurst = 20
+)
+
+var (
+	// ErrNoSnapshot is returned when responding if there is no current snapshot of the backend.
+	ErrNoSnapshot = errors.New("no current snapshot of backend")
+
+	// ErrUnservable is wrapped by the errors passed to the error handler for
+	// values of the snapshot which were truncated or skipped as they can't be served.
+	ErrUnservable = errors.New("value can't be served")
+
+	// backendOptions are the client options used to query the backend by
+	// protocol, which request all of the state which can be served.
+	backendOptions = map[string][]svrquery.Option{
+		"sqp": {svrquery.WithArg(sqp.ChunksArg, "info+rules+players+teams+metrics")},
+	}
+)
+
+// Option represents a Proxy option.
+type Option func(*Proxy) error
+
+// Proxy answers queries using a snapshot of the state of a backend server,
+// which is refreshed periodically.
+type Proxy struct {
+	backendProto  string
+	backend       string
+	proto         string
+	interval      time.Duration
+	maxAge        time.Duration
+	rate          float64
+	burst         int
+	clientOptions []svrquery.Option
+	serverOptions []svrsample.Option
+	errorHandler  func(err error)
+	state         *common.SyncState
+	server        *svrsample.Server
+
+	mtx     sync.RWMutex
+	updated time.Time
+}
+
+// WithProtocol sets the protocol used to answer queries, by default the protocol of the backend.
+func WithProtocol(proto string) Option {
+	return func(p *Proxy) error {
+		p.proto = proto
+		return nil
+	}
+}
+
+// WithInterval sets the interval at which the backend is queried.
+func WithInterval(interval time.Duration) Option {
+	return func(p *Proxy) error {
+		if interval <= 0 {
+			return fmt.Errorf("invalid interval %v", interval)
+		}
+		p.interval = interval
+		return nil
+	}
+}
+
+// WithMaxAge sets the maximum age of a snapshot which is used to answer
+// queries, after which queries aren't answered until the backend responds.
+func WithMaxAge(maxAge time.Duration) Option {
+	return func(p *Proxy) error {
+		if maxAge <= 0 {
+			return fmt.Errorf("invalid max age %v", maxAge)
+		}
+		p.maxAge = maxAge
+		return nil
+	}
+}
+
+// WithRateLimit limits the requests from each IP address to rate per second
+// with bursts of up to burst requests. A rate of zero disables the limit.
+func WithRateLimit(rate float64, burst int) Option {
+	return func(p *Proxy) error {
+		if rate < 0 {
+			return fmt.Errorf("invalid rate %v", rate)
+		}
+		p.rate = rate
+		p.burst = burst
+		return nil
+	}
+}
+
+// WithClientOptions sets options of the client used to query the backend e.g. svrquery.WithKey.
+func WithClientOptions(options ...svrquery.Option) Option {
+	return func(p *Proxy) error {
+		p.clientOptions = append(p.clientOptions, options...)
+		return nil
+	}
+}
+
+// WithServerOptions sets options of the server which answers queries e.g. svrsample.WithAllow.
+func WithServerOptions(options ...svrsample.Option) Option {
+	return func(p *Proxy) error {
+		p.serverOptions = append(p.serverOptions, options...)
+		return nil
+	}
+}
+
+// WithErrorHandler sets the handler called with errors querying the backend,
+// and for values of its snapshot which were truncated or skipped as they
+// can't be served, which wrap ErrUnservable, see StateFromResponse.
+func WithErrorHandler(h func(err error)) Option {
+	return func(p *Proxy) error {
+		p.errorHandler = h
+		return nil
+	}
+}
+
+// New creates a new Proxy which answers queries on addr using a snapshot
+// of the backend server at address backend, queried using proto.
+func New(proto, backend, addr string, options ...Option) (*Proxy, error) {
+	p := &Proxy{
+		backendProto: proto,
+		backend:      backend,
+		proto:        proto,
+		interval:     DefaultInterval,
+		maxAge:       DefaultMaxAge,
+		rate:         DefaultRate,
+		burst:        DefaultBurst,
+		errorHandler: func(error) {},
+		state:        common.NewSyncState(common.QueryState{}),
+	}
+
+	for _, o := range options {
+		if err := o(p); err != nil {
+			return nil, err
+		}
+	}
+
+	responder, err := svrsample.GetProviderResponder(p.proto, p.state)
+	if err != nil {
+		return nil, err
+	}
+
+	serverOptions := p.serverOptions
+	if p.rate > 0 {
+		serverOptions = append(serverOptions, svrsample.WithRateLimit(p.rate, p.burst))
+	}
+
+	if p.server, err = svrsample.NewServer(addr, &snapshotResponder{proxy: p, responder: responder}, serverOptions...); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Listen starts listening on the address of the proxy if it isn't already.
+// It only needs to be called before Serve if Addr is required first.
+func (p *Proxy) Listen() error {
+	return p.server.Listen()
+}
+
+// Addr returns the address the proxy is listening on, nil if it isn't.
+func (p *Proxy) Addr() net.Addr {
+	return p.server.Addr()
+}
+
+// Stats returns the counters of the packets handled by the proxy.
+func (p *Proxy) Stats() svrsample.Stats {
+	return p.server.Stats()
+}
+
+// Snapshot returns the current snapshot of the backend and when it was
+// taken, which is zero if there is none.
+func (p *Proxy) Snapshot() (common.QueryState, time.Time) {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+	return p.state.State(), p.updated
+}
+
+// Refresh queries the backend, replacing the snapshot if successful.
+func (p *Proxy) Refresh() error {
+	options := append(append([]svrquery.Option(nil), backendOptions[p.backendProto]...), p.clientOptions...)
+	c, err := svrquery.NewClient(p.backendProto, p.backend, options...)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	resp, err := c.Query()
+	if err != nil {
+		return fmt.Errorf("query backend %s: %w", p.backend, err)
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.state.UpdateState(stateFromResponse(resp, func(err error) {
+		p.errorHandler(fmt.Errorf("snapshot of backend %s: %w: %w", p.backend, ErrUnservable, err))
+	}))
+	p.updated = time.Now()
+	return nil
+}
+
+// Serve listens, if not already, queries the backend every interval and
+// answers queries until ctx is done or the proxy is shutdown, in which case
+// nil is returned.
+func (p *Proxy) Serve(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	go func() {
+		defer close(done)
+		p.poll(ctx)
+	}()
+
+	return p.server.Serve(ctx)
+}
+
+// Shutdown stops the proxy and waits for queries being answered to
+// complete or ctx to be done.
+func (p *Proxy) Shutdown(ctx context.Context) error {
+	return p.server.Shutdown(ctx)
+}
+
+// poll refreshes the snapshot immediately and then every interval until ctx is done.
+func (p *Proxy) poll(ctx context.Context) {
+	t := time.NewTicker(p.interval)
+	defer t.Stop()
+	for {
+		if err := p.Refresh(); err != nil {
+			p.errorHandler(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// fresh returns true if the snapshot can be used to answer queries.
+func (p *Proxy) fresh() bool {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+	return !p.updated.IsZero() && time.Since(p.updated) <= p.maxAge
+}
+
+// snapshotResponder is a common.MultiPacketResponder which only responds
+// if the proxy has a current snapshot.
+type snapshotResponder struct {
+	proxy     *Proxy
+	responder common.QueryResponder
+}
+
+// Respond implements common.QueryResponder.
+func (r *snapshotResponder) Respond(clientAddress string, buf []byte) ([]byte, error) {
+	if !r.proxy.fresh() {
+		return nil, ErrNoSnapshot
+	}
+	return r.responder.Respond(clientAddress, buf)
+}
+
+// VerifiesAddress implements common.AddressVerifier using the wrapped responder, if it supports it.
+func (r *snapshotResponder) VerifiesAddress(req []byte) bool {
+	v, ok := r.responder.(common.AddressVerifier)
+	return ok && v.VerifiesAddress(req)
+}
+
+// RespondPackets implements common.MultiPacketResponder.
+func (r *snapshotResponder) RespondPackets(clientAddress string, buf []byte) ([][]byte, error) {
+	if !r.proxy.fresh() {
+		return nil, ErrNoSnapshot
+	}
+
+	if m, ok := r.responder.(common.MultiPacketResponder); ok {
+		return m.RespondPackets(clientAddress, buf)
+	}
+
+	resp, err := r.responder.Respond(clientAddress, buf)
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{resp}, nil
+}
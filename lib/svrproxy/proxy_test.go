@@ -0,0 +1,159 @@
+package svrproxy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/multiplay/go-svrquery/lib/svrquery"
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol/sqp"
+	"github.com/multiplay/go-svrquery/lib/svrsample"
+	"github.com/multiplay/go-svrquery/lib/svrsample/common"
+	"github.com/stretchr/testify/require"
+)
+
+// newBackend starts a sample sqp server with state returning it.
+func newBackend(t *testing.T, state *common.SyncState) *svrsample.Server {
+	t.Helper()
+	r, err := svrsample.GetProviderResponder("sqp", state)
+	require.NoError(t, err)
+
+	s, err := svrsample.NewServer("127.0.0.1:0", r)
+	require.NoError(t, err)
+	require.NoError(t, s.Listen())
+	go s.Serve(context.Background())                       // nolint: errcheck
+	t.Cleanup(func() { s.Shutdown(context.Background()) }) // nolint: errcheck
+	return s
+}
+
+// newProxy starts a proxy in front of backend returning it.
+func newProxy(t *testing.T, backend string, options ...Option) *Proxy {
+	t.Helper()
+	p, err := New("sqp", backend, "127.0.0.1:0", options...)
+	require.NoError(t, err)
+	require.NoError(t, p.Listen())
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- p.Serve(context.Background())
+	}()
+	t.Cleanup(func() {
+		require.NoError(t, p.Shutdown(context.Background()))
+		require.NoError(t, <-errc)
+	})
+	return p
+}
+
+// query queries addr with all chunks.
+func query(addr string) (*sqp.QueryResponse, error) {
+	c, err := svrquery.NewClient("sqp", addr,
+		svrquery.WithTimeout(time.Millisecond*200),
+		svrquery.WithArg(sqp.ChunksArg, "info+rules+players+metrics"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	resp, err := c.Query()
+	if err != nil {
+		return nil, err
+	}
+	return resp.(*sqp.QueryResponse), nil
+}
+
+func TestProxy(t *testing.T) {
+	state := common.NewSyncState(common.QueryState{
+		CurrentPlayers: 1,
+		MaxPlayers:     8,
+		ServerName:     "Name",
+		Map:            "dust",
+		Rules:          map[string]common.DynamicValue{"mode": common.NewString("ranked")},
+		Players:        []common.Record{{"name": common.NewString("alice"), "score": common.NewUint32(10)}},
+		Metrics:        []float32{1.5},
+	})
+	backend := newBackend(t, state)
+
+	errs := make(chan error, 10)
+	p := newProxy(t, backend.Addr().String(),
+		WithInterval(time.Millisecond*20),
+		WithErrorHandler(func(err error) { errs <- err }),
+	)
+	require.Eventually(t, func() bool {
+		_, updated := p.Snapshot()
+		return !updated.IsZero()
+	}, time.Second, time.Millisecond*10)
+
+	resp, err := query(p.Addr().String())
+	require.NoError(t, err)
+	require.Equal(t, "Name", resp.ServerInfo.ServerName)
+	require.Equal(t, "ranked", resp.ServerRules.Rules["mode"].String())
+	require.Equal(t, uint32(10), resp.PlayerInfo.Players[0]["score"].Uint32())
+	require.Equal(t, []float32{1.5}, resp.Metrics.Metrics)
+
+	// Changes to the backend are reflected after the next refresh.
+	state.Update(func(qs *common.QueryState) {
+		qs.Map = "harbour"
+	})
+	require.Eventually(t, func() bool {
+		resp, err := query(p.Addr().String())
+		return err == nil && resp.ServerInfo.Map == "harbour"
+	}, time.Second, time.Millisecond*10)
+
+	require.Positive(t, p.Stats().Responded)
+	require.Empty(t, errs)
+}
+
+func TestProxyBackendDown(t *testing.T) {
+	backend := newBackend(t, common.NewSyncState(common.QueryState{MaxPlayers: 8}))
+
+	errs := make(chan error, 100)
+	p := newProxy(t, backend.Addr().String(),
+		WithInterval(time.Millisecond*10),
+		WithMaxAge(time.Millisecond*50),
+		WithClientOptions(svrquery.WithTimeout(time.Millisecond*10)),
+		WithErrorHandler(func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		}),
+	)
+	require.Eventually(t, func() bool {
+		_, err := query(p.Addr().String())
+		return err == nil
+	}, time.Second, time.Millisecond*10)
+
+	// Once the snapshot is too old queries aren't answered.
+	require.NoError(t, backend.Shutdown(context.Background()))
+	require.Eventually(t, func() bool {
+		_, err := query(p.Addr().String())
+		return errors.Is(err, protocol.ErrTimeout)
+	}, time.Second, time.Millisecond*10)
+	require.ErrorIs(t, <-errs, protocol.ErrUnreachable)
+}
+
+func TestProxyRateLimit(t *testing.T) {
+	backend := newBackend(t, common.NewSyncState(common.QueryState{MaxPlayers: 8}))
+	p := newProxy(t, backend.Addr().String(), WithRateLimit(0.1, 2))
+	require.Eventually(t, func() bool {
+		_, updated := p.Snapshot()
+		return !updated.IsZero()
+	}, time.Second, time.Millisecond*10)
+
+	// A query uses the burst of two requests.
+	_, err := query(p.Addr().String())
+	require.NoError(t, err)
+	_, err = query(p.Addr().String())
+	require.ErrorIs(t, err, protocol.ErrTimeout)
+	require.Equal(t, uint64(1), p.Stats().Limited)
+}
+
+func TestNewOptions(t *testing.T) {
+	for _, o := range []Option{WithInterval(0), WithMaxAge(0), WithRateLimit(-1, 1), WithProtocol("foo")} {
+		_, err := New("sqp", "127.0.0.1:1", ":0", o)
+		require.Error(t, err)
+	}
+}
@@ -0,0 +1,206 @@
+package svrproxy
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol/sqp"
+	"github.com/multiplay/go-svrquery/lib/svrsample/common"
+)
+
+// maxStringLength is the maximum length of a string responders can encode.
+const maxStringLength = math.MaxUint8
+
+// StateFromResponse returns the state of the server which returned resp.
+// SQP responses are converted completely, other protocols provide the
+// player counts and, if supported, the map, rules and players.
+//
+// Values which responders can't encode are truncated or skipped, so the
+// state can always be served: strings are truncated to 255 bytes and record
+// fields which aren't in every record with the same type are skipped.
+func StateFromResponse(resp protocol.Responser) common.QueryState {
+	return stateFromResponse(resp, func(error) {})
+}
+
+// stateFromResponse is StateFromResponse calling report with each change
+// made to values which can't be encoded.
+func stateFromResponse(resp protocol.Responser, report func(error)) common.QueryState {
+	s := &sanitizer{report: report}
+	if r, ok := resp.(*sqp.QueryResponse); ok {
+		return s.stateFromSQP(r)
+	}
+
+	state := common.QueryState{
+		CurrentPlayers: clampInt32(resp.NumClients()),
+		MaxPlayers:     clampInt32(resp.MaxClients()),
+	}
+
+	if m, ok := resp.(protocol.Mapper); ok {
+		state.Map = s.string("map", m.Map())
+	}
+
+	if r, ok := resp.(protocol.Ruler); ok {
+		rules := r.Rules()
+		state.Rules = make(map[string]common.DynamicValue, len(rules))
+		for k, v := range rules {
+			state.Rules[s.string("rule name", k)] = common.NewString(s.string(fmt.Sprintf("rule %q", k), v))
+		}
+	}
+
+	if p, ok := resp.(protocol.PlayerLister); ok {
+		for _, player := range p.Players() {
+			state.Players = append(state.Players, common.Record{
+				"name":  common.NewString(s.string("player name", player.Name)),
+				"score": common.NewUint32(clampUint32(player.Score)),
+				"ping":  common.NewUint32(clampUint32(player.Ping)),
+			})
+		}
+	}
+
+	return state
+}
+
+// sanitizer converts values to those responders can encode, reporting changes.
+type sanitizer struct {
+	report func(error)
+}
+
+// stateFromSQP returns the state of all the chunks of r.
+func (s *sanitizer) stateFromSQP(r *sqp.QueryResponse) common.QueryState {
+	var state common.QueryState
+	if si := r.ServerInfo; si != nil {
+		state.CurrentPlayers = int32(si.CurrentPlayers)
+		state.MaxPlayers = int32(si.MaxPlayers)
+		state.ServerName = s.string("server name", si.ServerName)
+		state.GameType = s.string("game type", si.GameType)
+		state.BuildID = s.string("build id", si.BuildID)
+		state.Map = s.string("map", si.Map)
+		state.Port = si.Port
+	}
+
+	if r.ServerRules != nil {
+		state.Rules = make(map[string]common.DynamicValue, len(r.ServerRules.Rules))
+		for k, v := range r.ServerRules.Rules {
+			state.Rules[s.string("rule name", k)] = s.dynamicValue(fmt.Sprintf("rule %q", k), v)
+		}
+	}
+
+	if r.PlayerInfo != nil {
+		state.Players = s.records("players", r.PlayerInfo.Players)
+	}
+
+	if r.TeamInfo != nil {
+		state.Teams = s.records("teams", r.TeamInfo.Teams)
+	}
+
+	if r.Metrics != nil {
+		state.Metrics = append([]float32(nil), r.Metrics.Metrics...)
+	}
+
+	return state
+}
+
+// records returns the SQP records as common.Record. Responders require
+// every record to have the same fields, so fields which aren't in every
+// record with the same type are skipped.
+func (s *sanitizer) records(what string, records []map[string]*sqp.DynamicValue) []common.Record {
+	if len(records) == 0 {
+		return []common.Record{}
+	} else if len(records) > math.MaxUint16 {
+		s.report(fmt.Errorf("%s: skipped %d records over the limit of %d", what, len(records)-math.MaxUint16, math.MaxUint16))
+		records = records[:math.MaxUint16]
+	}
+
+	names := make(map[string]struct{})
+	for _, r := range records {
+		for k := range r {
+			names[k] = struct{}{}
+		}
+	}
+
+	fields := make([]string, 0, len(names))
+	for k := range names {
+		if s.commonField(records, k) {
+			fields = append(fields, k)
+		} else {
+			s.report(fmt.Errorf("%s: skipped field %q which isn't in every record with the same type", what, k))
+		}
+	}
+	sort.Strings(fields)
+
+	if len(fields) > math.MaxUint8 {
+		s.report(fmt.Errorf("%s: skipped %d fields over the limit of %d", what, len(fields)-math.MaxUint8, math.MaxUint8))
+		fields = fields[:math.MaxUint8]
+	}
+
+	if len(fields) == 0 {
+		s.report(fmt.Errorf("%s: skipped %d records with no common fields", what, len(records)))
+		return []common.Record{}
+	}
+
+	res := make([]common.Record, len(records))
+	for i, r := range records {
+		res[i] = make(common.Record, len(fields))
+		for _, k := range fields {
+			res[i][s.string(what+" field name", k)] = s.dynamicValue(fmt.Sprintf("%s field %q", what, k), r[k])
+		}
+	}
+	return res
+}
+
+// commonField returns true if every record has the field name with the same type.
+func (s *sanitizer) commonField(records []map[string]*sqp.DynamicValue, name string) bool {
+	for _, r := range records {
+		if v, ok := r[name]; !ok || v == nil || v.Type != records[0][name].Type {
+			return false
+		}
+	}
+	return true
+}
+
+// dynamicValue returns the SQP value dv as a common.DynamicValue, whose types match.
+func (s *sanitizer) dynamicValue(what string, dv *sqp.DynamicValue) common.DynamicValue {
+	if v, ok := dv.Value.(string); ok {
+		return common.NewString(s.string(what, v))
+	}
+	return common.DynamicValue{Type: common.ValueType(dv.Type), Value: dv.Value}
+}
+
+// string returns v truncated to the maximum string length, at a rune boundary.
+func (s *sanitizer) string(what, v string) string {
+	if len(v) <= maxStringLength {
+		return v
+	}
+
+	n := maxStringLength
+	for n > 0 && !utf8.RuneStart(v[n]) {
+		n--
+	}
+	s.report(fmt.Errorf("%s: truncated %d bytes to %d", what, len(v), n))
+	return v[:n]
+}
+
+// clampInt32 returns v limited to the range of an int32.
+func clampInt32(v int64) int32 {
+	switch {
+	case v > math.MaxInt32:
+		return math.MaxInt32
+	case v < math.MinInt32:
+		return math.MinInt32
+	}
+	return int32(v)
+}
+
+// clampUint32 returns v limited to the range of a uint32.
+func clampUint32(v int64) uint32 {
+	switch {
+	case v > math.MaxUint32:
+		return math.MaxUint32
+	case v < 0:
+		return 0
+	}
+	return uint32(v)
+}
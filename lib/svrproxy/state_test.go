@@ -0,0 +1,84 @@
+package svrproxy
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol/sqp"
+	"github.com/multiplay/go-svrquery/lib/svrsample/common"
+	sqpsample "github.com/multiplay/go-svrquery/lib/svrsample/protocol/sqp"
+	"github.com/stretchr/testify/require"
+)
+
+// response is a protocol.Responser supporting the optional interfaces.
+type response struct{}
+
+func (response) NumClients() int64        { return 1 }
+func (response) MaxClients() int64        { return 1 << 40 }
+func (response) Map() string              { return "dust" }
+func (response) Rules() map[string]string { return map[string]string{"mode": "ranked"} }
+func (response) Players() []protocol.Player {
+	return []protocol.Player{{Name: "alice", Score: -1, Ping: 20}}
+}
+
+func TestStateFromResponse(t *testing.T) {
+	require.Equal(t, common.QueryState{
+		CurrentPlayers: 1,
+		MaxPlayers:     1<<31 - 1,
+		Map:            "dust",
+		Rules:          map[string]common.DynamicValue{"mode": common.NewString("ranked")},
+		Players: []common.Record{{
+			"name":  common.NewString("alice"),
+			"score": common.NewUint32(0),
+			"ping":  common.NewUint32(20),
+		}},
+	}, StateFromResponse(response{}))
+}
+
+func TestStateFromResponseUnencodable(t *testing.T) {
+	long := strings.Repeat("a", 254) + "é"
+	str := func(s string) *sqp.DynamicValue { return &sqp.DynamicValue{Type: sqp.String, Value: s} }
+	u32 := func(v uint32) *sqp.DynamicValue { return &sqp.DynamicValue{Type: sqp.Uint32, Value: v} }
+
+	resp := &sqp.QueryResponse{
+		ServerInfo:  &sqp.ServerInfoChunk{CurrentPlayers: 2, MaxPlayers: 8, ServerName: long},
+		ServerRules: &sqp.ServerRulesChunk{Rules: map[string]*sqp.DynamicValue{"motd": str(long)}},
+		PlayerInfo: &sqp.PlayerInfoChunk{Players: []map[string]*sqp.DynamicValue{
+			{"name": str("alice"), "score": u32(10), "team": str("red")},
+			{"name": str("bob"), "score": str("7"), "kills": u32(1)},
+		}},
+	}
+
+	var reports []string
+	state := stateFromResponse(resp, func(err error) {
+		reports = append(reports, err.Error())
+	})
+
+	// The é would be split, so is removed entirely.
+	truncated := strings.Repeat("a", 254)
+	require.Equal(t, truncated, state.ServerName)
+	require.Equal(t, common.NewString(truncated), state.Rules["motd"])
+	require.Equal(t, []common.Record{
+		{"name": common.NewString("alice")},
+		{"name": common.NewString("bob")},
+	}, state.Players)
+	require.ElementsMatch(t, []string{
+		"server name: truncated 256 bytes to 254",
+		`rule "motd": truncated 256 bytes to 254`,
+		`players: skipped field "score" which isn't in every record with the same type`,
+		`players: skipped field "team" which isn't in every record with the same type`,
+		`players: skipped field "kills" which isn't in every record with the same type`,
+	}, reports)
+
+	// The state can be served.
+	r, err := sqpsample.NewQueryResponder(state)
+	require.NoError(t, err)
+	challenge, err := r.Respond("addr", []byte{0, 0, 0, 0, 0})
+	require.NoError(t, err)
+	// Query the server info, rules and players chunks.
+	_, err = r.RespondPackets("addr", bytes.Join([][]byte{{1}, challenge[1:5], {0, 1}, {0x7}}, nil))
+	require.NoError(t, err)
+	require.Equal(t, state, StateFromResponse(resp))
+}
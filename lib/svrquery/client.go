@@ -1,7 +1,11 @@
 package svrquery
 
 import (
+	"errors"
+	"fmt"
 	"net"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
@@ -27,9 +31,14 @@ type Client struct {
 	addr     string
 	ua       *net.UDPAddr
 	key      string
+	args     map[string]interface{}
 	timeout  time.Duration
-	c        *net.UDPConn
+	c        net.Conn
 	protocol.Queryer
+
+	// exchanges are the exchanges of the current query and name is the name of the next.
+	exchanges []protocol.Exchange
+	name      string
 }
 
 // WithKey sets the key used for request by for the client.
@@ -40,6 +49,17 @@ func WithKey(key string) Option {
 	}
 }
 
+// WithArg sets a protocol specific argument for the client.
+func WithArg(key string, value interface{}) Option {
+	return func(c *Client) error {
+		if c.args == nil {
+			c.args = make(map[string]interface{})
+		}
+		c.args[key] = value
+		return nil
+	}
+}
+
 // WithTimeout sets the read and write timeout for the client.
 func WithTimeout(t time.Duration) Option {
 	return func(c *Client) error {
@@ -61,7 +81,6 @@ func NewClient(proto, addr string, options ...Option) (*Client, error) {
 		network:  DefaultNetwork,
 		timeout:  DefaultTimeout,
 	}
-	c.Queryer = f(c)
 
 	for _, o := range options {
 		if err := o(c); err != nil {
@@ -69,12 +88,25 @@ func NewClient(proto, addr string, options ...Option) (*Client, error) {
 		}
 	}
 
+	c.Queryer = f(c)
+	if n, ok := c.Queryer.(protocol.Networker); ok {
+		c.network = n.Network()
+	}
+
+	if !strings.HasPrefix(c.network, "udp") {
+		// Stream based protocol.
+		if c.c, err = net.DialTimeout(c.network, addr, c.timeout); err != nil {
+			return nil, netError(err)
+		}
+		return c, nil
+	}
+
 	if c.ua, err = net.ResolveUDPAddr(c.network, addr); err != nil {
-		return nil, err
+		return nil, netError(err)
 	}
 
 	if c.c, err = net.DialUDP(c.network, nil, c.ua); err != nil {
-		return nil, err
+		return nil, netError(err)
 	}
 
 	return c, nil
@@ -86,26 +118,149 @@ func (c *Client) Write(b []byte) (int, error) {
 		return 0, err
 	}
 
-	return c.c.Write(b)
+	sent := time.Now()
+	n, err := c.c.Write(b)
+	if err != nil {
+		return n, netError(err)
+	}
+
+	c.exchanges = append(c.exchanges, protocol.Exchange{Name: c.name, Sent: sent})
+	c.name = ""
+	return n, nil
 }
 
 // Read implements io.Reader.
 func (c *Client) Read(b []byte) (int, error) {
-	if err := c.c.SetReadDeadline(time.Now().Add(c.timeout)); err != nil {
+	return c.read(b, c.timeout)
+}
+
+// ReadTimeout implements protocol.TimeoutReader, the timeout is limited to
+// the client's read timeout.
+func (c *Client) ReadTimeout(b []byte, timeout time.Duration) (int, error) {
+	if timeout > c.timeout {
+		timeout = c.timeout
+	}
+	return c.read(b, timeout)
+}
+
+// read reads the next response into b waiting up to timeout.
+func (c *Client) read(b []byte, timeout time.Duration) (int, error) {
+	if err := c.c.SetReadDeadline(time.Now().Add(timeout)); err != nil {
 		return 0, err
 	}
 
+	uc, ok := c.c.(*net.UDPConn)
+	if !ok {
+		n, err := c.c.Read(b)
+		if n > 0 {
+			c.received()
+		}
+		return n, c.readError(err)
+	}
+
 	for {
-		n, addr, err := c.c.ReadFromUDP(b)
+		n, addr, err := uc.ReadFromUDP(b)
 		if err != nil {
-			return 0, err
+			return 0, c.readError(err)
 		} else if addr.String() == c.ua.String() { // We use String as IP's can be different byte but the same value.
+			c.received()
 			return n, nil
 		}
 		// Packet from unexpected source just ignore.
 	}
 }
 
+// received records the receipt of a response to the oldest exchange still awaiting one.
+func (c *Client) received() {
+	for i := range c.exchanges {
+		if c.pending(i) {
+			c.exchanges[i].Received = time.Now()
+			return
+		}
+	}
+}
+
+// readError returns netError(err), marking the exchanges awaiting a response
+// as timed out if the read timed out, so later responses are recorded
+// against the exchanges they answer.
+func (c *Client) readError(err error) error {
+	err = netError(err)
+	if errors.Is(err, protocol.ErrTimeout) {
+		for i := range c.exchanges {
+			if c.pending(i) {
+				c.exchanges[i].TimedOut = true
+			}
+		}
+	}
+	return err
+}
+
+// pending returns true if exchange i is still awaiting a response.
+func (c *Client) pending(i int) bool {
+	return c.exchanges[i].Received.IsZero() && !c.exchanges[i].TimedOut
+}
+
+// Query implements protocol.Queryer, recording the exchanges it performs.
+func (c *Client) Query() (protocol.Responser, error) {
+	c.reset()
+	return c.Queryer.Query()
+}
+
+// Ping performs the cheapest round trip supported by the protocol and returns
+// the round trip time of its first exchange. Protocols which don't implement
+// protocol.Pinger perform a full query.
+func (c *Client) Ping() (time.Duration, error) {
+	c.reset()
+	var err error
+	if p, ok := c.Queryer.(protocol.Pinger); ok {
+		err = p.Ping()
+	} else {
+		_, err = c.Queryer.Query()
+	}
+	if err != nil {
+		return 0, err
+	} else if len(c.exchanges) == 0 {
+		return 0, nil
+	}
+
+	return c.exchanges[0].RTT(), nil
+}
+
+// reset clears the exchanges of the previous query.
+func (c *Client) reset() {
+	c.exchanges = nil
+	c.name = ""
+}
+
+// Exchanges implements protocol.Latencyer, returning the exchanges of the last query or ping.
+func (c *Client) Exchanges() []protocol.Exchange {
+	return c.exchanges
+}
+
+// NameExchange implements protocol.ExchangeNamer.
+func (c *Client) NameExchange(name string) {
+	c.name = name
+}
+
+// netError wraps err with protocol.ErrTimeout or protocol.ErrUnreachable
+// if it's the cause, otherwise err is returned unchanged.
+func netError(err error) error {
+	var de *net.DNSError
+	var ne net.Error
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &de),
+		errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.EHOSTUNREACH),
+		errors.Is(err, syscall.ENETUNREACH):
+		return fmt.Errorf("%w: %w", protocol.ErrUnreachable, err)
+	case errors.As(err, &ne) && ne.Timeout():
+		return fmt.Errorf("%w: %w", protocol.ErrTimeout, err)
+	}
+	return err
+}
+
 // Close implements io.Closer.
 func (c *Client) Close() error {
 	return c.c.Close()
@@ -116,11 +271,21 @@ func (c *Client) Key() string {
 	return c.key
 }
 
+// Args implements protocol.Argser.
+func (c *Client) Args() map[string]interface{} {
+	return c.args
+}
+
 // Address implements protocol.Client.
 func (c *Client) Address() string {
 	return c.addr
 }
 
+// RemoteAddr implements protocol.RemoteAddrer.
+func (c *Client) RemoteAddr() net.Addr {
+	return c.c.RemoteAddr()
+}
+
 // Protocol returns the protocol of the client.
 func (c *Client) Protocol() string {
 	return c.protocol
@@ -2,10 +2,13 @@ package svrquery
 
 import (
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"testing"
 	"time"
 
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
 	"github.com/stretchr/testify/require"
 )
 
@@ -41,6 +44,34 @@ func TestNewClient(t *testing.T) {
 	}
 }
 
+func TestNewClientStream(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer l.Close()
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = io.Copy(conn, conn)
+	}()
+
+	c, err := NewClient("frostbite", l.Addr().String(), WithTimeout(time.Second))
+	require.NoError(t, err)
+	defer c.Close()
+	require.IsType(t, &net.TCPConn{}, c.c)
+
+	_, err = c.Write([]byte("ping"))
+	require.NoError(t, err)
+
+	b := make([]byte, 4)
+	_, err = io.ReadFull(c, b)
+	require.NoError(t, err)
+	require.Equal(t, "ping", string(b))
+}
+
 func TestQuery(t *testing.T) {
 	addr := os.Getenv("TEST_QUERY_ADDR")
 	if addr == "" {
@@ -60,3 +91,117 @@ func TestQuery(t *testing.T) {
 		fmt.Printf("%#v\n", r)
 	}
 }
+
+func TestClientErrors(t *testing.T) {
+	silent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer silent.Close()
+
+	c, err := NewClient("sqp", silent.LocalAddr().String(), WithTimeout(time.Millisecond*10))
+	require.NoError(t, err)
+	defer c.Close()
+
+	_, err = c.Query()
+	require.ErrorIs(t, err, protocol.ErrTimeout)
+
+	closed, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	require.NoError(t, closed.Close())
+
+	c, err = NewClient("sqp", closed.LocalAddr().String(), WithTimeout(time.Millisecond*100))
+	require.NoError(t, err)
+	defer c.Close()
+
+	_, err = c.Query()
+	require.ErrorIs(t, err, protocol.ErrUnreachable)
+
+	_, err = NewClient("frostbite", "127.0.0.1:1", WithTimeout(time.Millisecond*100))
+	require.ErrorIs(t, err, protocol.ErrUnreachable)
+
+	_, err = NewClient("sqp", "host.invalid:1")
+	require.ErrorIs(t, err, protocol.ErrUnreachable)
+}
+
+func TestClientReadTimeout(t *testing.T) {
+	silent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer silent.Close()
+
+	c, err := NewClient("sqp", silent.LocalAddr().String(), WithTimeout(time.Second*10))
+	require.NoError(t, err)
+	defer c.Close()
+
+	start := time.Now()
+	_, err = c.ReadTimeout(make([]byte, 10), time.Millisecond*10)
+	require.ErrorIs(t, err, protocol.ErrTimeout)
+	require.Less(t, time.Since(start), time.Second)
+}
+
+func TestClientExchangesTimeout(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pc.Close()
+
+	// Only respond to the second request.
+	go func() {
+		b := make([]byte, 10)
+		for {
+			n, addr, err := pc.ReadFrom(b)
+			if err != nil {
+				return
+			} else if string(b[:n]) == "second" {
+				_, _ = pc.WriteTo(b[:n], addr)
+			}
+		}
+	}()
+
+	c, err := NewClient("sqp", pc.LocalAddr().String(), WithTimeout(time.Millisecond*50))
+	require.NoError(t, err)
+	defer c.Close()
+
+	b := make([]byte, 10)
+	_, err = c.Write([]byte("first"))
+	require.NoError(t, err)
+	_, err = c.Read(b)
+	require.ErrorIs(t, err, protocol.ErrTimeout)
+
+	_, err = c.Write([]byte("second"))
+	require.NoError(t, err)
+	_, err = c.Read(b)
+	require.NoError(t, err)
+
+	e := c.Exchanges()
+	require.Len(t, e, 2)
+	require.True(t, e[0].TimedOut)
+	require.Zero(t, e[0].RTT())
+	require.False(t, e[1].TimedOut)
+	require.Positive(t, e[1].RTT())
+	require.False(t, e[1].Received.Before(e[1].Sent))
+}
+
+func TestClientExchanges(t *testing.T) {
+	c, err := NewClient("sqp", sqpServer(t, 1))
+	require.NoError(t, err)
+	defer c.Close()
+
+	_, err = c.Query()
+	require.NoError(t, err)
+
+	e := c.Exchanges()
+	require.Len(t, e, 2)
+	require.Equal(t, "challenge", e[0].Name)
+	require.Equal(t, "query", e[1].Name)
+	for _, x := range e {
+		require.Positive(t, x.RTT())
+	}
+	require.False(t, e[1].Sent.Before(e[0].Received))
+
+	rtt, err := c.Ping()
+	require.NoError(t, err)
+	require.Positive(t, rtt)
+
+	e = c.Exchanges()
+	require.Len(t, e, 1)
+	require.Equal(t, "challenge", e[0].Name)
+	require.Equal(t, rtt, e[0].RTT())
+}
@@ -0,0 +1,224 @@
+package valvemaster
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+)
+
+// Option represents a Client option.
+type Option func(*Client) error
+
+// Client provides the ability to discover servers from a master server.
+type Client struct {
+	addr     string
+	timeout  time.Duration
+	interval time.Duration
+	retries  int
+}
+
+// Result is a discovered server address or an error which ended discovery.
+type Result struct {
+	Address string
+	Err     error
+}
+
+// WithTimeout sets the read and write timeout for the client.
+func WithTimeout(t time.Duration) Option {
+	return func(c *Client) error {
+		c.timeout = t
+		return nil
+	}
+}
+
+// WithInterval sets the minimum interval between page requests.
+func WithInterval(i time.Duration) Option {
+	return func(c *Client) error {
+		c.interval = i
+		return nil
+	}
+}
+
+// WithRetries sets the number of times a page request is retried if no response is received.
+func WithRetries(n int) Option {
+	return func(c *Client) error {
+		if n < 0 {
+			return fmt.Errorf("invalid retries %d", n)
+		}
+		c.retries = n
+		return nil
+	}
+}
+
+// NewClient creates a new client that talks to the master server at addr.
+func NewClient(addr string, options ...Option) (*Client, error) {
+	c := &Client{
+		addr:     addr,
+		timeout:  DefaultTimeout,
+		interval: DefaultInterval,
+		retries:  DefaultRetries,
+	}
+
+	for _, o := range options {
+		if err := o(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// Servers discovers the addresses of servers in region which match filter
+// and streams them on the returned channel, which is closed once discovery
+// completes. If discovery fails the last Result contains the error.
+func (c *Client) Servers(ctx context.Context, region Region, filter Filter) <-chan Result {
+	results := make(chan Result)
+	go func() {
+		defer close(results)
+		if err := c.servers(ctx, region, filter.String(), results); err != nil {
+			select {
+			case results <- Result{Err: err}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return results
+}
+
+// servers requests pages of addresses, each seeded by the last address of
+// the previous page, until the terminating address is received.
+func (c *Client) servers(ctx context.Context, region Region, filter string, results chan<- Result) error {
+	conn, err := net.Dial("udp", c.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Unblock reads if the context is cancelled.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Now()) // nolint: errcheck
+		case <-done:
+		}
+	}()
+
+	seed := seedAddress
+	var last time.Time
+	for {
+		if err := sleep(ctx, c.interval-time.Since(last)); err != nil {
+			return err
+		}
+		last = time.Now()
+
+		addrs, err := c.page(ctx, conn, region, seed, filter)
+		if err != nil {
+			return err
+		}
+
+		for _, a := range addrs {
+			if a == seedAddress {
+				return nil
+			}
+
+			select {
+			case results <- Result{Address: a}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		if len(addrs) == 0 {
+			return errors.New("empty page")
+		}
+		seed = addrs[len(addrs)-1]
+	}
+}
+
+// sleep blocks for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// page requests a page of addresses, retrying with backoff if no response is received.
+func (c *Client) page(ctx context.Context, conn net.Conn, region Region, seed, filter string) ([]string, error) {
+	req := make([]byte, 0, 2+len(seed)+1+len(filter)+1)
+	req = append(req, queryRequest, byte(region))
+	req = append(req, seed...)
+	req = append(req, 0)
+	req = append(req, filter...)
+	req = append(req, 0)
+
+	b := make([]byte, packetSize)
+	backoff := c.interval
+	if backoff < minBackoff {
+		backoff = minBackoff
+	}
+	for attempt := 0; ; attempt++ {
+		if err := conn.SetDeadline(time.Now().Add(c.timeout)); err != nil {
+			return nil, err
+		} else if _, err = conn.Write(req); err != nil {
+			return nil, fmt.Errorf("write: %w", err)
+		}
+
+		n, err := conn.Read(b)
+		if err == nil {
+			return parsePage(b[:n])
+		} else if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		var ne net.Error
+		if !errors.As(err, &ne) || !ne.Timeout() {
+			return nil, fmt.Errorf("read: %w", err)
+		} else if attempt >= c.retries {
+			return nil, fmt.Errorf("%w: read: %w", protocol.ErrTimeout, err)
+		}
+
+		// No response is usually the result of rate limiting so backoff.
+		backoff *= 2
+		if err = sleep(ctx, backoff); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parsePage parses the addresses from a response packet.
+func parsePage(b []byte) ([]string, error) {
+	if !bytes.HasPrefix(b, responseHeader) {
+		return nil, fmt.Errorf("%w: unexpected header (len: %d)", protocol.ErrMalformed, len(b))
+	}
+
+	b = b[len(responseHeader):]
+	if len(b)%entryLength != 0 {
+		return nil, fmt.Errorf("%w: invalid entries length %d", protocol.ErrMalformed, len(b))
+	}
+
+	addrs := make([]string, 0, len(b)/entryLength)
+	for ; len(b) > 0; b = b[entryLength:] {
+		ip := net.IP(b[:4])
+		port := binary.BigEndian.Uint16(b[4:])
+		addrs = append(addrs, net.JoinHostPort(ip.String(), strconv.Itoa(int(port))))
+	}
+	return addrs, nil
+}
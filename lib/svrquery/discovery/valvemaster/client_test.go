@@ -0,0 +1,174 @@
+package valvemaster
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+// master is a local stand-in for a master server.
+type master struct {
+	conn     net.PacketConn
+	servers  []string
+	pageSize int
+	drop     int
+
+	mtx      sync.Mutex
+	requests [][]byte
+}
+
+func newMaster(t *testing.T, pageSize, drop int, servers ...string) *master {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	m := &master{conn: conn, servers: servers, pageSize: pageSize, drop: drop}
+	go m.serve()
+	return m
+}
+
+func (m *master) serve() {
+	b := make([]byte, packetSize)
+	for {
+		n, addr, err := m.conn.ReadFrom(b)
+		if err != nil {
+			return
+		}
+
+		req := append([]byte(nil), b[:n]...)
+		m.mtx.Lock()
+		m.requests = append(m.requests, req)
+		drop := len(m.requests) <= m.drop
+		m.mtx.Unlock()
+		if drop {
+			continue
+		}
+
+		seed := string(req[2 : bytes.IndexByte(req[2:], 0)+2])
+		start := 0
+		for i, s := range m.servers {
+			if s == seed {
+				start = i + 1
+			}
+		}
+
+		resp := append([]byte(nil), responseHeader...)
+		end := start + m.pageSize
+		page := m.servers[start:]
+		if end < len(m.servers) {
+			page = m.servers[start:end]
+		} else {
+			page = append(page[:len(page):len(page)], seedAddress)
+		}
+		for _, s := range page {
+			host, port, _ := net.SplitHostPort(s)
+			p, _ := strconv.Atoi(port)
+			resp = append(resp, net.ParseIP(host).To4()...)
+			resp = binary.BigEndian.AppendUint16(resp, uint16(p))
+		}
+		m.conn.WriteTo(resp, addr) // nolint: errcheck
+	}
+}
+
+func (m *master) addr() string {
+	return m.conn.LocalAddr().String()
+}
+
+func collect(t *testing.T, results <-chan Result) ([]string, error) {
+	t.Helper()
+	var addrs []string
+	for r := range results {
+		if r.Err != nil {
+			return addrs, r.Err
+		}
+		addrs = append(addrs, r.Address)
+	}
+	return addrs, nil
+}
+
+func TestServers(t *testing.T) {
+	servers := []string{"10.0.0.1:27015", "10.0.0.2:27016", "192.168.1.1:28015", "172.16.0.1:27015", "8.8.8.8:1"}
+	m := newMaster(t, 2, 0, servers...)
+
+	c, err := NewClient(m.addr(), WithInterval(time.Millisecond))
+	require.NoError(t, err)
+
+	f := Filter{{"gamedir", "rust"}, {"empty", "1"}}
+	addrs, err := collect(t, c.Servers(context.Background(), Europe, f))
+	require.NoError(t, err)
+	require.Equal(t, servers, addrs)
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	require.Len(t, m.requests, 3)
+	require.Equal(t, "\x31\x030.0.0.0:0\x00\\gamedir\\rust\\empty\\1\x00", string(m.requests[0]))
+	require.Equal(t, "\x31\x0310.0.0.2:27016\x00\\gamedir\\rust\\empty\\1\x00", string(m.requests[1]))
+	require.Equal(t, "\x31\x03172.16.0.1:27015\x00\\gamedir\\rust\\empty\\1\x00", string(m.requests[2]))
+}
+
+func TestServersRetry(t *testing.T) {
+	servers := []string{"10.0.0.1:27015", "10.0.0.2:27016"}
+	m := newMaster(t, 10, 1, servers...)
+
+	c, err := NewClient(m.addr(), WithInterval(0), WithTimeout(time.Millisecond*50))
+	require.NoError(t, err)
+
+	start := time.Now()
+	addrs, err := collect(t, c.Servers(context.Background(), RestOfWorld, nil))
+	require.NoError(t, err)
+	require.Equal(t, servers, addrs)
+
+	// Retries back off even without an interval.
+	require.GreaterOrEqual(t, time.Since(start), minBackoff*2)
+}
+
+func TestServersTimeout(t *testing.T) {
+	m := newMaster(t, 10, 10)
+
+	c, err := NewClient(m.addr(), WithInterval(time.Millisecond), WithTimeout(time.Millisecond*10), WithRetries(1))
+	require.NoError(t, err)
+
+	_, err = collect(t, c.Servers(context.Background(), RestOfWorld, nil))
+	require.ErrorIs(t, err, protocol.ErrTimeout)
+}
+
+func TestServersCancel(t *testing.T) {
+	m := newMaster(t, 10, 10)
+
+	c, err := NewClient(m.addr(), WithInterval(time.Millisecond))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+
+	start := time.Now()
+	for range c.Servers(ctx, RestOfWorld, nil) {
+	}
+	require.Less(t, time.Since(start), DefaultTimeout)
+}
+
+func TestParsePage(t *testing.T) {
+	addrs, err := parsePage(append(append([]byte(nil), responseHeader...), 1, 2, 3, 4, 0x69, 0x87, 0, 0, 0, 0, 0, 0))
+	require.NoError(t, err)
+	require.Equal(t, []string{"1.2.3.4:27015", seedAddress}, addrs)
+
+	_, err = parsePage([]byte{0xFF, 0xFF})
+	require.ErrorIs(t, err, protocol.ErrMalformed)
+
+	_, err = parsePage(append(append([]byte(nil), responseHeader...), 1, 2, 3))
+	require.Error(t, err)
+}
+
+func TestFilter(t *testing.T) {
+	require.Equal(t, "", Filter(nil).String())
+	require.Equal(t, `\gamedir\rust\empty\1`, Filter{{"gamedir", "rust"}, {"empty", "1"}}.String())
+}
@@ -0,0 +1,60 @@
+package valvemaster
+
+import (
+	"time"
+)
+
+// Region is the region code used to restrict discovered servers.
+type Region byte
+
+// Regions.
+const (
+	USEastCoast  Region = 0x00
+	USWestCoast  Region = 0x01
+	SouthAmerica Region = 0x02
+	Europe       Region = 0x03
+	Asia         Region = 0x04
+	Australia    Region = 0x05
+	MiddleEast   Region = 0x06
+	Africa       Region = 0x07
+	RestOfWorld  Region = 0xFF
+)
+
+var (
+	// DefaultAddress is the address of the Valve master server.
+	DefaultAddress = "hl2master.steampowered.com:27011"
+
+	// DefaultTimeout is the default read and write timeout.
+	DefaultTimeout = time.Second * 3
+
+	// DefaultInterval is the default minimum interval between page requests.
+	// The master server drops requests from clients which exceed its rate limit.
+	DefaultInterval = time.Second
+
+	// DefaultRetries is the default number of times a page request is retried
+	// if no response is received.
+	DefaultRetries = 3
+
+	// minBackoff is the minimum delay before a page request is retried, so
+	// a master server which rate limits isn't flooded with retries.
+	minBackoff = time.Millisecond * 100
+
+	// seedAddress is the address which starts and terminates a query.
+	seedAddress = "0.0.0.0:0"
+)
+
+const (
+	// queryRequest is the type of a query request packet.
+	queryRequest = byte(0x31)
+
+	// entryLength is the size of an address entry in a response.
+	entryLength = 6
+
+	// packetSize is the maximum size of a response packet.
+	packetSize = 1400
+)
+
+var (
+	// responseHeader is the prefix of a query response packet.
+	responseHeader = []byte{0xFF, 0xFF, 0xFF, 0xFF, 0x66, 0x0A}
+)
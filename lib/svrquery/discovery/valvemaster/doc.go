@@ -0,0 +1,3 @@
+// Package valvemaster provides a client for the Valve Master Server Query
+// Protocol, which is used to discover the addresses of public servers.
+package valvemaster
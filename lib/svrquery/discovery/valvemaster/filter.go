@@ -0,0 +1,21 @@
+package valvemaster
+
+import (
+	"strings"
+)
+
+// Filter is a master server filter made up of key value pairs
+// such as {{"gamedir", "rust"}, {"empty", "1"}}.
+type Filter [][2]string
+
+// String returns the wire representation of the filter e.g. \gamedir\rust\empty\1.
+func (f Filter) String() string {
+	var b strings.Builder
+	for _, kv := range f {
+		b.WriteByte('\\')
+		b.WriteString(kv[0])
+		b.WriteByte('\\')
+		b.WriteString(kv[1])
+	}
+	return b.String()
+}
@@ -0,0 +1,234 @@
+package svrquery
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+)
+
+var (
+	// DefaultConcurrency is the default number of queries QueryMany performs concurrently.
+	DefaultConcurrency = 100
+)
+
+// Target represents a server to query.
+type Target struct {
+	// Protocol is the protocol used to query the server.
+	Protocol string
+
+	// Address is the address of the server.
+	Address string
+
+	// Options are the options used to create the client for the server.
+	Options []Option
+
+	// Retries is the number of times a query which times out or fails to
+	// reach the server is retried.
+	Retries int
+}
+
+// Result is the result of querying a Target.
+type Result struct {
+	// Index is the index of the Target in the targets passed to QueryMany.
+	Index int
+
+	// Target is the server which was queried.
+	Target Target
+
+	// Response is the response from the server, nil if Err is set or the server was pinged.
+	Response protocol.Responser
+
+	// Exchanges are the exchanges with the server of the last attempt.
+	Exchanges []protocol.Exchange
+
+	// Err is the error, if any, which occurred creating the client or querying the server.
+	Err error
+
+	// Start is the time the last attempt to query the server started.
+	Start time.Time
+
+	// Latency is the time taken by the last attempt to query the server.
+	Latency time.Duration
+
+	// Attempts is the number of times the server was queried.
+	Attempts int
+}
+
+// ManyOption represents a QueryMany option.
+type ManyOption func(*many)
+
+// many is the configuration for QueryMany.
+type many struct {
+	concurrency int
+	interval    time.Duration
+	ping        bool
+}
+
+// WithConcurrency sets the number of queries QueryMany performs concurrently.
+// Values less than one are ignored.
+func WithConcurrency(n int) ManyOption {
+	return func(m *many) {
+		if n > 0 {
+			m.concurrency = n
+		}
+	}
+}
+
+// WithRate limits the rate at which QueryMany starts queries to rate per second.
+// Values less than or equal to zero mean no limit. Intervals are limited to
+// between a nanosecond and the longest time.Duration.
+func WithRate(rate float64) ManyOption {
+	return func(m *many) {
+		if rate <= 0 || math.IsNaN(rate) {
+			m.interval = 0
+			return
+		}
+
+		switch d := float64(time.Second) / rate; {
+		case d >= math.MaxInt64:
+			m.interval = math.MaxInt64
+		case d < 1:
+			m.interval = 1
+		default:
+			m.interval = time.Duration(d)
+		}
+	}
+}
+
+// WithPing makes QueryMany ping targets, using the cheapest round trip of
+// their protocol, instead of querying them.
+func WithPing() ManyOption {
+	return func(m *many) {
+		m.ping = true
+	}
+}
+
+// QueryMany queries targets concurrently, streaming a Result for each target
+// on the returned channel in the order they complete. The channel is closed
+// once all targets have been queried or ctx is done, in which case targets
+// which have not been queried produce no Result.
+func QueryMany(ctx context.Context, targets []Target, options ...ManyOption) <-chan Result {
+	m := &many{concurrency: DefaultConcurrency}
+	for _, o := range options {
+		o(m)
+	}
+
+	jobs := make(chan int)
+	results := make(chan Result)
+
+	var wg sync.WaitGroup
+	for w := 0; w < m.concurrency && w < len(targets); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				r := queryTarget(ctx, i, targets[i], m.ping)
+				select {
+				case results <- r:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		var tick <-chan time.Time
+		if m.interval > 0 {
+			t := time.NewTicker(m.interval)
+			defer t.Stop()
+			tick = t.C
+		}
+
+		for i := range targets {
+			if tick != nil && i > 0 {
+				select {
+				case <-tick:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
+
+// queryTarget queries a single target, retrying queries which fail due to a
+// timeout or an unreachable server up to t.Retries times.
+func queryTarget(ctx context.Context, i int, t Target, ping bool) Result {
+	r := Result{Index: i, Target: t}
+	for r.Attempts <= t.Retries && ctx.Err() == nil {
+		r.Attempts++
+		r.Start = time.Now()
+		r.Response, r.Exchanges, r.Latency, r.Err = queryOnce(ctx, t, ping)
+		if !retryable(r.Err) {
+			break
+		}
+	}
+	if r.Attempts == 0 {
+		r.Err = ctx.Err()
+	}
+	return r
+}
+
+// retryable returns true if err is a failure which may not occur if retried.
+func retryable(err error) bool {
+	var de *net.DNSError
+	switch {
+	case errors.As(err, &de):
+		return de.IsTimeout || de.IsTemporary
+	case errors.Is(err, protocol.ErrTimeout), errors.Is(err, protocol.ErrUnreachable):
+		return true
+	}
+	return false
+}
+
+// queryOnce queries or pings a target, aborting if ctx is done.
+func queryOnce(ctx context.Context, t Target, ping bool) (resp protocol.Responser, exchanges []protocol.Exchange, latency time.Duration, err error) {
+	start := time.Now()
+	defer func() {
+		latency = time.Since(start)
+	}()
+
+	c, err := NewClient(t.Protocol, t.Address, t.Options...)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	defer c.Close()
+
+	// Unblock the query if the context is cancelled.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.Close() // nolint: errcheck
+		case <-done:
+		}
+	}()
+
+	if ping {
+		_, err = c.Ping()
+	} else {
+		resp, err = c.Query()
+	}
+	return resp, c.Exchanges(), 0, err
+}
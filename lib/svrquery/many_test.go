@@ -0,0 +1,191 @@
+package svrquery
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+	"github.com/multiplay/go-svrquery/lib/svrsample/common"
+	"github.com/multiplay/go-svrquery/lib/svrsample/protocol/sqp"
+	"github.com/stretchr/testify/require"
+)
+
+// sqpServer starts a sample sqp server returning its address.
+func sqpServer(t *testing.T, players int32) string {
+	t.Helper()
+	r, err := sqp.NewQueryResponder(common.QueryState{CurrentPlayers: players, MaxPlayers: 10, Map: "map"})
+	require.NoError(t, err)
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		b := make([]byte, 16)
+		for {
+			n, addr, err := conn.ReadFrom(b)
+			if err != nil {
+				return
+			}
+			resp, err := r.Respond(addr.String(), b[:n])
+			if err != nil {
+				continue
+			}
+			conn.WriteTo(resp, addr) // nolint: errcheck
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+// silentServer starts a server which never responds returning its address.
+func silentServer(t *testing.T) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn.LocalAddr().String()
+}
+
+func TestQueryMany(t *testing.T) {
+	targets := []Target{
+		{Protocol: "sqp", Address: sqpServer(t, 1)},
+		{Protocol: "sqp", Address: sqpServer(t, 2)},
+		{Protocol: "my-protocol", Address: "127.0.0.1:1"},
+		{Protocol: "sqp", Address: silentServer(t), Options: []Option{WithTimeout(time.Millisecond * 50)}},
+		{Protocol: "sqp", Address: sqpServer(t, 3)},
+	}
+
+	seen := make(map[int]Result)
+	for r := range QueryMany(context.Background(), targets, WithConcurrency(2)) {
+		require.NotContains(t, seen, r.Index)
+		require.Equal(t, targets[r.Index].Address, r.Target.Address)
+		seen[r.Index] = r
+	}
+	require.Len(t, seen, len(targets))
+
+	for i, players := range map[int]int64{0: 1, 1: 2, 4: 3} {
+		require.NoError(t, seen[i].Err)
+		require.Equal(t, players, seen[i].Response.NumClients())
+		require.Positive(t, seen[i].Latency)
+		require.False(t, seen[i].Start.IsZero())
+	}
+
+	require.Error(t, seen[2].Err)
+	require.Nil(t, seen[2].Response)
+
+	require.Error(t, seen[3].Err)
+	require.GreaterOrEqual(t, seen[3].Latency, time.Millisecond*50)
+}
+
+func TestQueryManyRetries(t *testing.T) {
+	targets := []Target{
+		{Protocol: "sqp", Address: sqpServer(t, 1), Retries: 2},
+		{Protocol: "sqp", Address: silentServer(t), Retries: 2, Options: []Option{WithTimeout(time.Millisecond * 20)}},
+		{Protocol: "my-protocol", Address: "127.0.0.1:1", Retries: 2},
+	}
+
+	seen := make(map[int]Result)
+	for r := range QueryMany(context.Background(), targets) {
+		seen[r.Index] = r
+	}
+	require.Len(t, seen, len(targets))
+
+	require.NoError(t, seen[0].Err)
+	require.Equal(t, 1, seen[0].Attempts)
+
+	require.Error(t, seen[1].Err)
+	require.Equal(t, 3, seen[1].Attempts)
+
+	// Permanent failures aren't retried.
+	require.Error(t, seen[2].Err)
+	require.Equal(t, 1, seen[2].Attempts)
+}
+
+func TestRetryable(t *testing.T) {
+	require.True(t, retryable(fmt.Errorf("%w: read", protocol.ErrTimeout)))
+	require.True(t, retryable(fmt.Errorf("%w: read", protocol.ErrUnreachable)))
+	require.True(t, retryable(fmt.Errorf("%w: %w", protocol.ErrUnreachable, &net.DNSError{IsTimeout: true})))
+	require.False(t, retryable(fmt.Errorf("%w: %w", protocol.ErrUnreachable, &net.DNSError{IsNotFound: true})))
+	require.False(t, retryable(fmt.Errorf("%w: bad key", protocol.ErrAuth)))
+	require.False(t, retryable(fmt.Errorf("%w: version 2", protocol.ErrUnsupportedVersion)))
+	require.False(t, retryable(nil))
+}
+
+func TestQueryManyPing(t *testing.T) {
+	targets := []Target{{Protocol: "sqp", Address: sqpServer(t, 1)}}
+	for r := range QueryMany(context.Background(), targets, WithPing()) {
+		require.NoError(t, r.Err)
+		require.Nil(t, r.Response)
+		require.Len(t, r.Exchanges, 1)
+		require.Positive(t, r.Exchanges[0].RTT())
+	}
+}
+
+func TestQueryManyRate(t *testing.T) {
+	targets := make([]Target, 5)
+	for i := range targets {
+		targets[i] = Target{Protocol: "my-protocol", Address: "127.0.0.1:1"}
+	}
+
+	start := time.Now()
+	var n int
+	for range QueryMany(context.Background(), targets, WithRate(100)) {
+		n++
+	}
+	require.Equal(t, len(targets), n)
+	require.GreaterOrEqual(t, time.Since(start), time.Millisecond*40)
+
+	// Rates beyond a nanosecond interval don't panic.
+	n = 0
+	for range QueryMany(context.Background(), targets, WithRate(1e12)) {
+		n++
+	}
+	require.Equal(t, len(targets), n)
+}
+
+func TestWithRate(t *testing.T) {
+	cases := []struct {
+		rate     float64
+		interval time.Duration
+	}{
+		{rate: 0, interval: 0},
+		{rate: -1, interval: 0},
+		{rate: math.NaN(), interval: 0},
+		{rate: 4, interval: time.Millisecond * 250},
+		{rate: 1e12, interval: 1},
+		{rate: 1e-12, interval: math.MaxInt64},
+		{rate: math.SmallestNonzeroFloat64, interval: math.MaxInt64},
+	}
+
+	for _, tc := range cases {
+		m := &many{}
+		WithRate(tc.rate)(m)
+		require.Equal(t, tc.interval, m.interval, tc.rate)
+	}
+}
+
+func TestQueryManyCancel(t *testing.T) {
+	targets := make([]Target, 10)
+	for i := range targets {
+		targets[i] = Target{Protocol: "sqp", Address: silentServer(t)}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+
+	start := time.Now()
+	for range QueryMany(ctx, targets, WithConcurrency(2)) {
+	}
+	require.Less(t, time.Since(start), DefaultTimeout)
+}
+
+func TestQueryManyEmpty(t *testing.T) {
+	for range QueryMany(context.Background(), nil) {
+		t.Fatal("unexpected result")
+	}
+}
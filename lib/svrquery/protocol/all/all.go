@@ -3,6 +3,14 @@ package all
 
 import (
 	// Register all known protocols
+	_ "github.com/multiplay/go-svrquery/lib/svrquery/protocol/ase"
+	_ "github.com/multiplay/go-svrquery/lib/svrquery/protocol/darkplaces"
+	_ "github.com/multiplay/go-svrquery/lib/svrquery/protocol/frostbite"
+	_ "github.com/multiplay/go-svrquery/lib/svrquery/protocol/mumble"
+	_ "github.com/multiplay/go-svrquery/lib/svrquery/protocol/samp"
+	_ "github.com/multiplay/go-svrquery/lib/svrquery/protocol/satisfactory"
 	_ "github.com/multiplay/go-svrquery/lib/svrquery/protocol/sqp"
+	_ "github.com/multiplay/go-svrquery/lib/svrquery/protocol/teeworlds"
 	_ "github.com/multiplay/go-svrquery/lib/svrquery/protocol/titanfall"
+	_ "github.com/multiplay/go-svrquery/lib/svrquery/protocol/ue2"
 )
@@ -0,0 +1,24 @@
+package ase
+
+// Player flags which indicate the fields present in a player entry.
+const (
+	PlayerName byte = 1 << iota
+	PlayerTeam
+	PlayerSkin
+	PlayerScore
+	PlayerPing
+	PlayerTime
+)
+
+var (
+	// serverInfoRequest is the request packet for server information.
+	serverInfoRequest = []byte{'s'}
+
+	// responseHeader is the prefix of a server information response.
+	responseHeader = []byte("EYE1")
+)
+
+const (
+	// packetSize is the maximum size of a response packet.
+	packetSize = 8192
+)
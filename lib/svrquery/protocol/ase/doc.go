@@ -0,0 +1,3 @@
+// Package ase provides the protocol implementation for the All-Seeing Eye
+// query protocol used by Multi Theft Auto and other older titles.
+package ase
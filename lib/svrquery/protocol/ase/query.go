@@ -0,0 +1,184 @@
+package ase
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/multiplay/go-svrquery/lib/svrquery/common"
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+)
+
+type queryer struct {
+	c protocol.Client
+}
+
+func newQueryer(c protocol.Client) protocol.Queryer {
+	return &queryer{c: c}
+}
+
+// Query implements protocol.Queryer.
+func (q *queryer) Query() (protocol.Responser, error) {
+	if _, err := q.c.Write(serverInfoRequest); err != nil {
+		return nil, fmt.Errorf("query write: %w", err)
+	}
+
+	b := make([]byte, packetSize)
+	n, err := q.c.Read(b)
+	if err != nil {
+		return nil, fmt.Errorf("query read: %w", err)
+	} else if !bytes.HasPrefix(b[:n], responseHeader) {
+		return nil, fmt.Errorf("%w: unexpected header (len: %d)", protocol.ErrMalformed, n)
+	}
+
+	i, err := q.parse(b[len(responseHeader):n])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", protocol.ErrMalformed, err)
+	}
+
+	return i, nil
+}
+
+// parse decodes the body of a response.
+func (q *queryer) parse(b []byte) (*Info, error) {
+	r := common.NewBinaryReader(b, binary.LittleEndian)
+	i := &Info{}
+	if err := q.serverInfo(r, i); err != nil {
+		return nil, err
+	} else if err = q.rules(r, i); err != nil {
+		return nil, err
+	} else if err = q.players(r, i); err != nil {
+		return nil, err
+	}
+
+	return i, nil
+}
+
+// serverInfo decodes the fixed server information fields from a response.
+func (q *queryer) serverInfo(r *common.BinaryReader, i *Info) (err error) {
+	var port, passworded, numPlayers, maxPlayers string
+	fields := []*string{
+		&i.GameName,
+		&port,
+		&i.ServerName,
+		&i.GameType,
+		&i.MapName,
+		&i.Version,
+		&passworded,
+		&numPlayers,
+		&maxPlayers,
+	}
+	for _, f := range fields {
+		if *f, err = readString(r); err != nil {
+			return err
+		}
+	}
+
+	p, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid port %q: %w", port, err)
+	}
+	i.Port = uint16(p)
+	i.Passworded = passworded == "1"
+
+	if i.NumPlayers, err = parseInt(numPlayers); err != nil {
+		return err
+	}
+	i.MaxPlayers, err = parseInt(maxPlayers)
+	return err
+}
+
+// rules decodes the key value rules from a response.
+// The rules are terminated by an empty key.
+func (q *queryer) rules(r *common.BinaryReader, i *Info) error {
+	i.ServerRules = make(map[string]string)
+	for {
+		k, err := readString(r)
+		if err != nil {
+			return err
+		} else if k == "" {
+			return nil
+		}
+
+		if i.ServerRules[k], err = readString(r); err != nil {
+			return err
+		}
+	}
+}
+
+// players decodes the players from a response, which continue until the
+// end of the packet.
+func (q *queryer) players(r *common.BinaryReader, i *Info) error {
+	for {
+		var flags byte
+		if err := r.Read(&flags); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		var p Player
+		fields := []struct {
+			flag byte
+			s    *string
+			v    *int64
+		}{
+			{flag: PlayerName, s: &p.Name},
+			{flag: PlayerTeam, s: &p.Team},
+			{flag: PlayerSkin, s: &p.Skin},
+			{flag: PlayerScore, v: &p.Score},
+			{flag: PlayerPing, v: &p.Ping},
+			{flag: PlayerTime, v: &p.Time},
+		}
+		for _, f := range fields {
+			if flags&f.flag == 0 {
+				continue
+			}
+
+			s, err := readString(r)
+			if err != nil {
+				return err
+			}
+
+			if f.s != nil {
+				*f.s = s
+			} else if *f.v, err = parseInt(s); err != nil {
+				return err
+			}
+		}
+		i.PlayerList = append(i.PlayerList, p)
+	}
+}
+
+// readString reads a string prefixed by a length byte which includes itself.
+func readString(r *common.BinaryReader) (string, error) {
+	var l byte
+	if err := r.Read(&l); err != nil {
+		return "", err
+	} else if l <= 1 {
+		return "", nil
+	}
+
+	b := make([]byte, l-1)
+	if err := r.Read(b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// parseInt parses s as a decimal integer treating empty as zero.
+func parseInt(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer %q: %w", s, err)
+	}
+	return v, nil
+}
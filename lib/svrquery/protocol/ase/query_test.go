@@ -0,0 +1,86 @@
+package ase
+
+import (
+	"testing"
+
+	"github.com/multiplay/go-svrquery/lib/svrquery/clienttest"
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testDir = "testdata"
+)
+
+func TestQuery(t *testing.T) {
+	cases := []struct {
+		name     string
+		response string
+		expected *Info
+		err      error
+	}{
+		{
+			name:     "info",
+			response: "info_response",
+			expected: &Info{
+				GameName:   "mta",
+				Port:       22003,
+				ServerName: "My Server",
+				GameType:   "Freeroam",
+				MapName:    "San Andreas",
+				Version:    "1.6",
+				NumPlayers: 2,
+				MaxPlayers: 32,
+				ServerRules: map[string]string{
+					"weather":  "sunny",
+					"gamemode": "race",
+				},
+				PlayerList: []Player{
+					{Name: "alice", Team: "red", Skin: "cj", Score: 10, Ping: 35, Time: 120},
+					{Name: "bob", Score: 3, Ping: 80},
+				},
+			},
+		},
+		{
+			name:     "invalid_header",
+			response: "info_invalid_response",
+			err:      protocol.ErrMalformed,
+		},
+		{
+			name:     "malformed",
+			response: "info_malformed_response",
+			err:      protocol.ErrMalformed,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := clienttest.LoadData(t, testDir, "info_request")
+			resp := clienttest.LoadData(t, testDir, tc.response)
+
+			mc := &clienttest.MockClient{}
+			mc.On("Write", req).Return(len(req), nil)
+			mc.On("Read", mock.AnythingOfType("[]uint8")).Return(resp, nil)
+
+			q := newQueryer(mc)
+			i, err := q.Query()
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, i)
+			mc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRulesPlayers(t *testing.T) {
+	i := &Info{
+		ServerRules: map[string]string{"mode": "ranked"},
+		PlayerList:  []Player{{Name: "alice", Team: "red", Score: 20, Ping: 50, Time: 60}},
+	}
+	require.Equal(t, map[string]string{"mode": "ranked"}, i.Rules())
+	require.Equal(t, []protocol.Player{{Name: "alice", Score: 20, Ping: 50}}, i.Players())
+}
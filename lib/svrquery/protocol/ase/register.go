@@ -0,0 +1,9 @@
+package ase
+
+import (
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+)
+
+func init() {
+	protocol.MustRegister("ase", newQueryer)
+}
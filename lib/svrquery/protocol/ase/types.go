@@ -0,0 +1,64 @@
+package ase
+
+import (
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+)
+
+// Info represents a full query response.
+type Info struct {
+	GameName    string            `json:"game_name"`
+	Port        uint16            `json:"port"`
+	ServerName  string            `json:"server_name"`
+	GameType    string            `json:"game_type"`
+	MapName     string            `json:"map"`
+	Version     string            `json:"version"`
+	Passworded  bool              `json:"passworded"`
+	NumPlayers  int64             `json:"num_players"`
+	MaxPlayers  int64             `json:"max_players"`
+	ServerRules map[string]string `json:"rules,omitempty"`
+	PlayerList  []Player          `json:"players,omitempty"`
+}
+
+// NumClients implements protocol.Responser.
+func (i *Info) NumClients() int64 {
+	return i.NumPlayers
+}
+
+// MaxClients implements protocol.Responser.
+func (i *Info) MaxClients() int64 {
+	return i.MaxPlayers
+}
+
+// Map implements protocol.Mapper.
+func (i *Info) Map() string {
+	return i.MapName
+}
+
+// Rules implements protocol.Ruler.
+func (i *Info) Rules() map[string]string {
+	return i.ServerRules
+}
+
+// Players implements protocol.PlayerLister.
+func (i *Info) Players() []protocol.Player {
+	players := make([]protocol.Player, len(i.PlayerList))
+	for j, p := range i.PlayerList {
+		players[j] = protocol.Player{
+			Name:  p.Name,
+			Score: p.Score,
+			Ping:  p.Ping,
+		}
+	}
+	return players
+}
+
+// Player represents a player in a query response.
+// Only the fields flagged by the server are populated.
+type Player struct {
+	Name  string `json:"name,omitempty"`
+	Team  string `json:"team,omitempty"`
+	Skin  string `json:"skin,omitempty"`
+	Score int64  `json:"score"`
+	Ping  int64  `json:"ping"`
+	Time  int64  `json:"time"`
+}
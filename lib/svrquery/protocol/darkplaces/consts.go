@@ -0,0 +1,29 @@
+package darkplaces
+
+const (
+	// InfoRequest is the command of a server information request.
+	InfoRequest = "getinfo"
+
+	// InfoResponse is the command of a server information response.
+	InfoResponse = "infoResponse"
+
+	// StatusRequest is the command of a status request.
+	StatusRequest = "getstatus"
+
+	// StatusResponse is the command of a status response.
+	StatusResponse = "statusResponse"
+
+	// challengeLength is the length of the challenge sent with requests.
+	challengeLength = 12
+
+	// challengeChars are the characters used to build a challenge.
+	challengeChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+	// packetSize is the maximum size of a response packet.
+	packetSize = 1400
+)
+
+var (
+	// oobPrefix is the prefix of all out of band packets.
+	oobPrefix = []byte{0xFF, 0xFF, 0xFF, 0xFF}
+)
@@ -0,0 +1,3 @@
+// Package darkplaces provides the protocol implementation for the DarkPlaces
+// engine used by Xonotic, Nexuiz and others.
+package darkplaces
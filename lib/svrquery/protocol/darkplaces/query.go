@@ -0,0 +1,211 @@
+package darkplaces
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+)
+
+type queryer struct {
+	c         protocol.Client
+	challenge func() string
+}
+
+func newQueryer(c protocol.Client) protocol.Queryer {
+	return &queryer{
+		c:         c,
+		challenge: newChallenge,
+	}
+}
+
+// newChallenge returns a random challenge.
+func newChallenge() string {
+	b := make([]byte, challengeLength)
+	for i := range b {
+		b[i] = challengeChars[rand.Intn(len(challengeChars))]
+	}
+	return string(b)
+}
+
+// Query implements protocol.Queryer.
+func (q *queryer) Query() (protocol.Responser, error) {
+	info, _, err := q.request(InfoRequest, InfoResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	i := &Info{
+		Hostname:    stripColors(info["hostname"]),
+		MapName:     info["mapname"],
+		GameName:    info["gamename"],
+		ModName:     info["modname"],
+		GameVersion: info["gameversion"],
+		QCStatus:    info["qcstatus"],
+	}
+	for k, v := range map[string]*int64{
+		"protocol":      &i.Protocol,
+		"clients":       &i.Clients,
+		"bots":          &i.Bots,
+		"sv_maxclients": &i.MaxPlayers,
+	} {
+		if *v, err = parseInt(info[k]); err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", protocol.ErrMalformed, k, err)
+		}
+	}
+
+	rules, lines, err := q.request(StatusRequest, StatusResponse)
+	if err != nil {
+		return nil, err
+	}
+	delete(rules, "challenge")
+	i.ServerRules = rules
+
+	for _, l := range lines {
+		p, err := parsePlayer(l)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", protocol.ErrMalformed, err)
+		}
+		i.PlayerList = append(i.PlayerList, p)
+	}
+
+	return i, nil
+}
+
+// Ping implements protocol.Pinger using only the info exchange.
+func (q *queryer) Ping() error {
+	_, _, err := q.request(InfoRequest, InfoResponse)
+	return err
+}
+
+// request sends the command with a new challenge and returns the infostring
+// and any additional lines of the response after verifying the echoed challenge.
+func (q *queryer) request(cmd, expected string) (map[string]string, []string, error) {
+	challenge := q.challenge()
+	req := append([]byte{}, oobPrefix...)
+	req = append(req, cmd+" "+challenge...)
+	protocol.NameExchange(q.c, cmd)
+	if _, err := q.c.Write(req); err != nil {
+		return nil, nil, fmt.Errorf("query write: %w", err)
+	}
+
+	b := make([]byte, packetSize)
+	n, err := q.c.Read(b)
+	if err != nil {
+		return nil, nil, fmt.Errorf("query read: %w", err)
+	} else if !bytes.HasPrefix(b[:n], oobPrefix) {
+		return nil, nil, fmt.Errorf("%w: unexpected header (len: %d)", protocol.ErrMalformed, n)
+	}
+
+	lines := strings.Split(strings.TrimRight(string(b[len(oobPrefix):n]), "\n"), "\n")
+	if lines[0] != expected {
+		return nil, nil, fmt.Errorf("%w: unexpected response %q", protocol.ErrMalformed, lines[0])
+	} else if len(lines) < 2 {
+		return nil, nil, fmt.Errorf("%w: missing infostring", protocol.ErrMalformed)
+	}
+
+	vars, err := parseInfoString(lines[1])
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", protocol.ErrMalformed, err)
+	} else if vars["challenge"] != challenge {
+		return nil, nil, fmt.Errorf("%w: unexpected challenge %q (expected %q)", protocol.ErrChallenge, vars["challenge"], challenge)
+	}
+
+	return vars, lines[2:], nil
+}
+
+// parseInfoString parses an infostring of the form \key\value\key\value.
+func parseInfoString(s string) (map[string]string, error) {
+	parts := strings.Split(strings.TrimPrefix(s, `\`), `\`)
+	if len(parts)%2 != 0 {
+		return nil, fmt.Errorf("infostring has odd number of fields (%d)", len(parts))
+	}
+
+	vars := make(map[string]string, len(parts)/2)
+	for i := 0; i < len(parts); i += 2 {
+		vars[parts[i]] = parts[i+1]
+	}
+	return vars, nil
+}
+
+// parsePlayer parses a status player line of the form
+// `score ping "name"` or `score ping "name" team`.
+func parsePlayer(l string) (p Player, err error) {
+	i, j := strings.IndexByte(l, '"'), strings.LastIndexByte(l, '"')
+	if i == j {
+		return p, fmt.Errorf("invalid player line %q", l)
+	}
+	p.Name = stripColors(l[i+1 : j])
+
+	fields := strings.Fields(l[:i])
+	if len(fields) != 2 {
+		return p, fmt.Errorf("invalid player line %q", l)
+	}
+
+	switch team := strings.Fields(l[j+1:]); len(team) {
+	case 0:
+	case 1:
+		fields = append(fields, team[0])
+	default:
+		return p, fmt.Errorf("invalid player line %q", l)
+	}
+
+	vals := []*int64{&p.Score, &p.Ping, &p.Team}
+	for k, f := range fields {
+		if *vals[k], err = parseInt(f); err != nil {
+			return p, err
+		}
+	}
+	return p, nil
+}
+
+// parseInt parses s as a decimal integer treating empty as zero.
+func parseInt(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer %q: %w", s, err)
+	}
+	return v, nil
+}
+
+// stripColors removes ^0-^9 and ^xRGB colour codes from s, unescaping ^^.
+func stripColors(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '^' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		switch c := s[i+1]; {
+		case c == '^':
+			b.WriteByte('^')
+			i++
+		case c >= '0' && c <= '9':
+			i++
+		case c == 'x' && i+4 < len(s) && isHex(s[i+2:i+5]):
+			i += 4
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// isHex returns true if s only contains hexadecimal digits.
+func isHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,139 @@
+package darkplaces
+
+import (
+	"testing"
+
+	"github.com/multiplay/go-svrquery/lib/svrquery/clienttest"
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testDir = "testdata"
+)
+
+func newTestQueryer(mc *clienttest.MockClient) *queryer {
+	challenges := []string{"chal1", "chal2"}
+	return &queryer{
+		c: mc,
+		challenge: func() string {
+			c := challenges[0]
+			challenges = challenges[1:]
+			return c
+		},
+	}
+}
+
+func TestQuery(t *testing.T) {
+	mc := &clienttest.MockClient{}
+	for _, name := range []string{"info", "status"} {
+		req := clienttest.LoadData(t, testDir, name+"_request")
+		resp := clienttest.LoadData(t, testDir, name+"_response")
+		mc.On("Write", req).Return(len(req), nil).Once()
+		mc.On("Read", mock.AnythingOfType("[]uint8")).Return(resp, nil).Once()
+	}
+
+	i, err := newTestQueryer(mc).Query()
+	require.NoError(t, err)
+	require.Equal(t, &Info{
+		Hostname:    "My Xonotic Server ^",
+		MapName:     "stormkeep",
+		GameName:    "Xonotic",
+		ModName:     "data",
+		GameVersion: "803",
+		Protocol:    3,
+		Clients:     3,
+		Bots:        1,
+		MaxPlayers:  16,
+		QCStatus:    "xonotic:0.8.6:P0:S2:F5:MXPM::score!!,kills,deaths",
+		ServerRules: map[string]string{
+			"gamename":               "Xonotic",
+			"sv_maxclients":          "16",
+			"g_balance_health_start": "100",
+			"mapname":                "stormkeep",
+		},
+		PlayerList: []Player{
+			{Name: "alice", Score: 12, Ping: 48, Team: 1},
+			{Name: "[BOT]bob", Score: -3, Ping: 0, Team: 2},
+			{Name: "carol^", Score: 0, Ping: 120},
+		},
+	}, i)
+	mc.AssertExpectations(t)
+}
+
+func TestPing(t *testing.T) {
+	mc := &clienttest.MockClient{}
+	req := clienttest.LoadData(t, testDir, "info_request")
+	resp := clienttest.LoadData(t, testDir, "info_response")
+	mc.On("Write", req).Return(len(req), nil).Once()
+	mc.On("Read", mock.AnythingOfType("[]uint8")).Return(resp, nil).Once()
+
+	require.NoError(t, newTestQueryer(mc).Ping())
+	mc.AssertExpectations(t)
+}
+
+func TestQueryChallengeMismatch(t *testing.T) {
+	mc := &clienttest.MockClient{}
+	req := clienttest.LoadData(t, testDir, "info_request")
+	resp := clienttest.LoadData(t, testDir, "info_invalid_response")
+	mc.On("Write", req).Return(len(req), nil)
+	mc.On("Read", mock.AnythingOfType("[]uint8")).Return(resp, nil)
+
+	_, err := newTestQueryer(mc).Query()
+	require.ErrorIs(t, err, protocol.ErrChallenge)
+}
+
+func TestStripColors(t *testing.T) {
+	cases := map[string]string{
+		"plain":          "plain",
+		"^1red^7white":   "redwhite",
+		"^xF0Agreen":     "green",
+		"^xZZZ":          "^xZZZ",
+		"a^^b":           "a^b",
+		"trailing^":      "trailing^",
+		"^^1escaped":     "^1escaped",
+		"^x12":           "^x12",
+		"^9^x123^0done^": "done^",
+	}
+	for in, exp := range cases {
+		require.Equal(t, exp, stripColors(in), in)
+	}
+}
+
+func TestRulesPlayers(t *testing.T) {
+	i := &Info{
+		ServerRules: map[string]string{"g_ctf": "1"},
+		PlayerList:  []Player{{Name: "alice", Score: 20, Ping: 50, Team: 1}},
+	}
+	require.Equal(t, map[string]string{"g_ctf": "1"}, i.Rules())
+	require.Equal(t, []protocol.Player{{Name: "alice", Score: 20, Ping: 50}}, i.Players())
+}
+
+func TestParsePlayer(t *testing.T) {
+	cases := map[string]struct {
+		line string
+		exp  Player
+		err  bool
+	}{
+		"no_team":      {line: `5 30 "alice"`, exp: Player{Name: "alice", Score: 5, Ping: 30}},
+		"team":         {line: `5 30 "alice" 2`, exp: Player{Name: "alice", Score: 5, Ping: 30, Team: 2}},
+		"quoted_name":  {line: `5 30 "a "b" c" -1`, exp: Player{Name: `a "b" c`, Score: 5, Ping: 30, Team: -1}},
+		"team_first":   {line: `5 30 2 "alice"`, err: true},
+		"missing_ping": {line: `5 "alice"`, err: true},
+		"unquoted":     {line: `5 30 alice`, err: true},
+		"extra":        {line: `5 30 "alice" 2 3`, err: true},
+		"bad_team":     {line: `5 30 "alice" red`, err: true},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			p, err := parsePlayer(tc.line)
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.exp, p)
+		})
+	}
+}
@@ -0,0 +1,9 @@
+package darkplaces
+
+import (
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+)
+
+func init() {
+	protocol.MustRegister("darkplaces", newQueryer)
+}
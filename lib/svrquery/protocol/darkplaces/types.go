@@ -0,0 +1,63 @@
+package darkplaces
+
+import (
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+)
+
+// Info represents a full query response.
+type Info struct {
+	Hostname    string            `json:"hostname"`
+	MapName     string            `json:"map"`
+	GameName    string            `json:"game_name"`
+	ModName     string            `json:"mod_name,omitempty"`
+	GameVersion string            `json:"game_version,omitempty"`
+	Protocol    int64             `json:"protocol"`
+	Clients     int64             `json:"clients"`
+	Bots        int64             `json:"bots"`
+	MaxPlayers  int64             `json:"max_clients"`
+	QCStatus    string            `json:"qcstatus,omitempty"`
+	ServerRules map[string]string `json:"rules,omitempty"`
+	PlayerList  []Player          `json:"players,omitempty"`
+}
+
+// NumClients implements protocol.Responser.
+func (i *Info) NumClients() int64 {
+	return i.Clients
+}
+
+// MaxClients implements protocol.Responser.
+func (i *Info) MaxClients() int64 {
+	return i.MaxPlayers
+}
+
+// Map implements protocol.Mapper.
+func (i *Info) Map() string {
+	return i.MapName
+}
+
+// Rules implements protocol.Ruler.
+func (i *Info) Rules() map[string]string {
+	return i.ServerRules
+}
+
+// Players implements protocol.PlayerLister.
+func (i *Info) Players() []protocol.Player {
+	players := make([]protocol.Player, len(i.PlayerList))
+	for j, p := range i.PlayerList {
+		players[j] = protocol.Player{
+			Name:  p.Name,
+			Score: p.Score,
+			Ping:  p.Ping,
+		}
+	}
+	return players
+}
+
+// Player represents a player in a status response.
+// Team is only reported by servers running a team game.
+type Player struct {
+	Name  string `json:"name"`
+	Score int64  `json:"score"`
+	Ping  int64  `json:"ping"`
+	Team  int64  `json:"team,omitempty"`
+}
@@ -0,0 +1,29 @@
+package protocol
+
+import (
+	"errors"
+)
+
+var (
+	// ErrTimeout is returned when a server doesn't respond in time.
+	ErrTimeout = errors.New("timeout")
+
+	// ErrUnreachable is returned when a server can't be reached, for example
+	// due to an ICMP port unreachable or its address not resolving.
+	ErrUnreachable = errors.New("unreachable")
+
+	// ErrAuth is returned when a server rejects or can't be authenticated
+	// with the key, for example due to a GCM open failure.
+	ErrAuth = errors.New("authentication failed")
+
+	// ErrMalformed is returned when a response can't be decoded.
+	ErrMalformed = errors.New("malformed response")
+
+	// ErrUnsupportedVersion is returned when a server responds with a protocol
+	// version which isn't supported.
+	ErrUnsupportedVersion = errors.New("unsupported version")
+
+	// ErrChallenge is returned when a response doesn't match the challenge,
+	// token or identifier of the request.
+	ErrChallenge = errors.New("challenge mismatch")
+)
@@ -0,0 +1,24 @@
+package frostbite
+
+const (
+	// Network is the network used by the protocol.
+	Network = "tcp"
+
+	// ResponseOK is the status word of a successful response.
+	ResponseOK = "OK"
+
+	// MaxPacketSize is the maximum size of a packet.
+	MaxPacketSize = 16384
+
+	// headerLength is the size of a packet header.
+	headerLength = 12
+
+	// flagFromClient indicates that a request originated from the client.
+	flagFromClient = uint32(1) << 31
+
+	// flagResponse indicates that a packet is a response.
+	flagResponse = uint32(1) << 30
+
+	// sequenceMask is the mask of the sequence number in a packet header.
+	sequenceMask = flagResponse - 1
+)
@@ -0,0 +1,3 @@
+// Package frostbite provides the protocol implementation for the Frostbite
+// remote administration protocol used by Battlefield 3, 4 and 1.
+package frostbite
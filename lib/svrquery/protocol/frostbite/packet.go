@@ -0,0 +1,74 @@
+package frostbite
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+)
+
+// packet represents a request or response.
+type packet struct {
+	Sequence uint32
+	Words    []string
+}
+
+// isResponse returns true if the packet is a response to a client request.
+func (p packet) isResponse() bool {
+	return p.Sequence&(flagFromClient|flagResponse) == flagFromClient|flagResponse
+}
+
+// marshal returns the wire encoding of the packet.
+func (p packet) marshal() []byte {
+	size := headerLength
+	for _, w := range p.Words {
+		size += 4 + len(w) + 1
+	}
+
+	b := make([]byte, headerLength, size)
+	binary.LittleEndian.PutUint32(b, p.Sequence)
+	binary.LittleEndian.PutUint32(b[4:], uint32(size))
+	binary.LittleEndian.PutUint32(b[8:], uint32(len(p.Words)))
+	for _, w := range p.Words {
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(w)))
+		b = append(b, w...)
+		b = append(b, 0)
+	}
+	return b
+}
+
+// readPacket reads a single packet from r.
+func readPacket(r io.Reader) (*packet, error) {
+	h := make([]byte, headerLength)
+	if _, err := io.ReadFull(r, h); err != nil {
+		return nil, err
+	}
+
+	size := binary.LittleEndian.Uint32(h[4:])
+	if size < headerLength || size > MaxPacketSize {
+		return nil, fmt.Errorf("%w: invalid packet size %d", protocol.ErrMalformed, size)
+	}
+
+	b := make([]byte, size-headerLength)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+
+	p := &packet{Sequence: binary.LittleEndian.Uint32(h)}
+	numWords := binary.LittleEndian.Uint32(h[8:])
+	for i := uint32(0); i < numWords; i++ {
+		if len(b) < 4 {
+			return nil, fmt.Errorf("%w: word %d: packet too short", protocol.ErrMalformed, i)
+		}
+
+		l := binary.LittleEndian.Uint32(b)
+		b = b[4:]
+		if uint32(len(b)) < l+1 || b[l] != 0 {
+			return nil, fmt.Errorf("%w: word %d: invalid length %d", protocol.ErrMalformed, i, l)
+		}
+		p.Words = append(p.Words, string(b[:l]))
+		b = b[l+1:]
+	}
+	return p, nil
+}
@@ -0,0 +1,277 @@
+package frostbite
+
+import (
+	"bufio"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+)
+
+type queryer struct {
+	c        protocol.Client
+	r        *bufio.Reader
+	sequence uint32
+	loggedIn bool
+}
+
+func newQueryer(c protocol.Client) protocol.Queryer {
+	return &queryer{
+		c: c,
+		r: bufio.NewReaderSize(c, MaxPacketSize),
+	}
+}
+
+// Network implements protocol.Networker.
+func (q *queryer) Network() string {
+	return Network
+}
+
+// Query implements protocol.Queryer.
+func (q *queryer) Query() (protocol.Responser, error) {
+	if q.c.Key() != "" && !q.loggedIn {
+		if err := q.login(); err != nil {
+			return nil, err
+		}
+		q.loggedIn = true
+	}
+
+	words, err := q.request("serverInfo")
+	if err != nil {
+		return nil, err
+	}
+
+	i := &Info{}
+	if err = decodeServerInfo(words, i); err != nil {
+		return nil, fmt.Errorf("%w: server info: %w", protocol.ErrMalformed, err)
+	}
+
+	if words, err = q.request("listPlayers", "all"); err != nil {
+		return nil, err
+	} else if i.PlayerList, err = decodePlayers(words); err != nil {
+		return nil, fmt.Errorf("%w: list players: %w", protocol.ErrMalformed, err)
+	}
+
+	return i, nil
+}
+
+// Ping implements protocol.Pinger using the version request, which doesn't require login.
+func (q *queryer) Ping() error {
+	_, err := q.request("version")
+	return err
+}
+
+// login authenticates using the hashed password exchange.
+func (q *queryer) login() error {
+	words, err := q.request("login.hashed")
+	if err != nil {
+		return err
+	} else if len(words) < 1 {
+		return fmt.Errorf("%w: login: missing salt", protocol.ErrMalformed)
+	}
+
+	salt, err := hex.DecodeString(words[0])
+	if err != nil {
+		return fmt.Errorf("%w: login: decode salt: %w", protocol.ErrMalformed, err)
+	}
+
+	h := md5.Sum(append(salt, q.c.Key()...))
+	if _, err = q.request("login.hashed", strings.ToUpper(hex.EncodeToString(h[:]))); err != nil {
+		var se statusError
+		if errors.As(err, &se) {
+			// The key was rejected.
+			return fmt.Errorf("%w: login: %w", protocol.ErrAuth, err)
+		}
+		return fmt.Errorf("login: %w", err)
+	}
+	return nil
+}
+
+// request sends a request and returns the words of its response following the status.
+func (q *queryer) request(words ...string) ([]string, error) {
+	seq := q.sequence & sequenceMask
+	q.sequence++
+
+	req := packet{Sequence: seq | flagFromClient, Words: words}
+	protocol.NameExchange(q.c, words[0])
+	if _, err := q.c.Write(req.marshal()); err != nil {
+		return nil, fmt.Errorf("query write: %w", err)
+	}
+
+	for {
+		p, err := readPacket(q.r)
+		if err != nil {
+			return nil, fmt.Errorf("query read: %w", err)
+		} else if !p.isResponse() || p.Sequence&sequenceMask != seq {
+			// Server event or stale response, ignore.
+			continue
+		}
+
+		if len(p.Words) == 0 {
+			return nil, fmt.Errorf("%w: %s: empty response", protocol.ErrMalformed, words[0])
+		} else if p.Words[0] != ResponseOK {
+			return nil, fmt.Errorf("%s: %w", words[0], statusError(p.Words[0]))
+		}
+		return p.Words[1:], nil
+	}
+}
+
+// statusError is a response status other than ResponseOK.
+type statusError string
+
+// Error implements error.
+func (e statusError) Error() string {
+	return string(e)
+}
+
+// wordReader provides sequential decoding of positional response words.
+type wordReader struct {
+	words []string
+	err   error
+}
+
+// next returns the next word, recording an error if there are none left.
+func (r *wordReader) next() string {
+	if r.err != nil {
+		return ""
+	} else if len(r.words) == 0 {
+		r.err = fmt.Errorf("too few words")
+		return ""
+	}
+
+	w := r.words[0]
+	r.words = r.words[1:]
+	return w
+}
+
+// optional returns the next word if present, otherwise empty.
+func (r *wordReader) optional() string {
+	if len(r.words) == 0 {
+		return ""
+	}
+	return r.next()
+}
+
+// int returns the next word as an integer.
+func (r *wordReader) int() int64 {
+	w := r.next()
+	if r.err != nil {
+		return 0
+	}
+
+	v, err := strconv.ParseInt(w, 10, 64)
+	if err != nil {
+		r.err = fmt.Errorf("invalid integer %q: %w", w, err)
+	}
+	return v
+}
+
+// bool returns the next word as a boolean.
+func (r *wordReader) bool() bool {
+	return r.next() == "true"
+}
+
+// decodeServerInfo decodes the positional words of a serverInfo response.
+func decodeServerInfo(words []string, i *Info) error {
+	r := &wordReader{words: words}
+	i.ServerName = r.next()
+	i.PlayerCount = r.int()
+	i.MaxPlayerCount = r.int()
+	i.GameMode = r.next()
+	i.MapName = r.next()
+	i.RoundsPlayed = r.int()
+	i.RoundsTotal = r.int()
+
+	n := r.int()
+	if r.err != nil {
+		return r.err
+	} else if n < 0 || n > int64(len(r.words)) {
+		return fmt.Errorf("invalid team count %d", n)
+	} else if n > 0 {
+		i.TeamScores = make([]int64, n)
+		for j := range i.TeamScores {
+			i.TeamScores[j] = r.int()
+		}
+	}
+	i.TargetScore = r.int()
+	i.OnlineState = r.next()
+	i.Ranked = r.bool()
+	i.PunkBuster = r.bool()
+	i.Passworded = r.bool()
+	i.ServerUpTime = r.int()
+	i.RoundTime = r.int()
+	if r.err != nil {
+		return r.err
+	}
+
+	// Later fields were added over time so may not be present.
+	i.GameAddress = r.optional()
+	i.PunkBusterVersion = r.optional()
+	i.JoinQueueEnabled = r.optional() == "true"
+	i.Region = r.optional()
+	i.ClosestPingSite = r.optional()
+	i.Country = r.optional()
+
+	return r.err
+}
+
+// decodePlayers decodes the words of a listPlayers response, which are
+// a field count, the field names, a player count and then the player values.
+func decodePlayers(words []string) ([]Player, error) {
+	r := &wordReader{words: words}
+	nf := r.int()
+	if r.err != nil {
+		return nil, r.err
+	} else if nf < 0 || nf > int64(len(r.words)) {
+		return nil, fmt.Errorf("invalid field count %d", nf)
+	}
+
+	fields := make([]string, nf)
+	for j := range fields {
+		fields[j] = r.next()
+	}
+
+	n := r.int()
+	if r.err != nil {
+		return nil, r.err
+	} else if n < 0 || n*nf > int64(len(r.words)) {
+		return nil, fmt.Errorf("invalid player count %d", n)
+	}
+
+	players := make([]Player, n)
+	for j := range players {
+		p := &players[j]
+		for _, f := range fields {
+			switch f {
+			case "name":
+				p.Name = r.next()
+			case "guid":
+				p.GUID = r.next()
+			case "teamId":
+				p.TeamID = r.int()
+			case "squadId":
+				p.SquadID = r.int()
+			case "kills":
+				p.Kills = r.int()
+			case "deaths":
+				p.Deaths = r.int()
+			case "score":
+				p.Score = r.int()
+			case "rank":
+				p.Rank = r.int()
+			case "ping":
+				p.Ping = r.int()
+			case "type":
+				p.Type = r.int()
+			default:
+				r.next()
+			}
+		}
+	}
+
+	return players, r.err
+}
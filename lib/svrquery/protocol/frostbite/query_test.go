@@ -0,0 +1,150 @@
+package frostbite
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/multiplay/go-svrquery/lib/svrquery/clienttest"
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testDir = "testdata"
+)
+
+var (
+	expected = &Info{
+		ServerName:        "My BF4 Server",
+		PlayerCount:       2,
+		MaxPlayerCount:    64,
+		GameMode:          "ConquestLarge0",
+		MapName:           "MP_Prison",
+		RoundsPlayed:      1,
+		RoundsTotal:       2,
+		TeamScores:        []int64{250, 300},
+		TargetScore:       0,
+		Ranked:            true,
+		PunkBuster:        true,
+		ServerUpTime:      3600,
+		RoundTime:         120,
+		GameAddress:       "1.2.3.4:25200",
+		PunkBusterVersion: "v1.905",
+		JoinQueueEnabled:  true,
+		Region:            "EU",
+		ClosestPingSite:   "i3",
+		Country:           "gb",
+		PlayerList: []Player{
+			{Name: "alice", GUID: "EA_1", TeamID: 1, SquadID: 2, Kills: 10, Deaths: 3, Score: 1500, Rank: 100, Ping: 35},
+			{Name: "bob", GUID: "EA_2", TeamID: 2, Kills: 0, Deaths: 5, Score: 200, Rank: 12, Ping: 65535},
+		},
+	}
+)
+
+func TestQuery(t *testing.T) {
+	cases := []struct {
+		name      string
+		key       string
+		exchanges []string
+		err       error
+	}{
+		{
+			name:      "anonymous",
+			exchanges: []string{"info", "players"},
+		},
+		{
+			name:      "keyed",
+			key:       "secret",
+			exchanges: []string{"login", "login_hashed", "info_keyed", "players_keyed"},
+		},
+		{
+			name:      "invalid_key",
+			key:       "secret",
+			exchanges: []string{"login", "login_hashed:login_invalid"},
+			err:       protocol.ErrAuth,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mc := &clienttest.MockClient{}
+			mc.On("Key").Return(tc.key)
+			for _, e := range tc.exchanges {
+				req, resp := e, e
+				if i := strings.IndexByte(e, ':'); i != -1 {
+					req, resp = e[:i], e[i+1:]
+				}
+				reqData := clienttest.LoadData(t, testDir, req+"_request")
+				respData := clienttest.LoadData(t, testDir, resp+"_response")
+				mc.On("Write", reqData).Return(len(reqData), nil).Once()
+				mc.On("Read", mock.AnythingOfType("[]uint8")).Return(respData, nil).Once()
+			}
+
+			q := newQueryer(mc)
+			i, err := q.Query()
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, expected, i)
+			require.Equal(t, int64(2), i.(*Info).Round())
+			mc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPacket(t *testing.T) {
+	p := packet{Sequence: flagFromClient | 5, Words: []string{"serverInfo", "", "a b"}}
+	b := p.marshal()
+	require.Len(t, b, headerLength+len("serverInfo")+len("a b")+3*5)
+
+	r, err := readPacket(bytes.NewReader(b))
+	require.NoError(t, err)
+	require.Equal(t, &p, r)
+	require.False(t, r.isResponse())
+
+	// Size too small for the words.
+	binary.LittleEndian.PutUint32(b[4:], headerLength+6)
+	_, err = readPacket(bytes.NewReader(b))
+	require.ErrorIs(t, err, protocol.ErrMalformed)
+}
+
+func TestDecodeServerInfoTooShort(t *testing.T) {
+	require.Error(t, decodeServerInfo([]string{"name", "2", "64"}, &Info{}))
+}
+
+func TestQueryInvalidTeamCount(t *testing.T) {
+	for _, n := range []string{"-1", "100"} {
+		t.Run(n, func(t *testing.T) {
+			resp := packet{
+				Sequence: flagFromClient | flagResponse,
+				Words:    []string{"OK", "name", "2", "64", "ConquestLarge0", "MP_Prison", "1", "2", n, "250", "300"},
+			}
+			mc := &clienttest.MockClient{}
+			mc.On("Key").Return("")
+			mc.On("Write", mock.AnythingOfType("[]uint8")).Return(0, nil).Once()
+			mc.On("Read", mock.AnythingOfType("[]uint8")).Return(resp.marshal(), nil).Once()
+
+			_, err := newQueryer(mc).Query()
+			require.ErrorIs(t, err, protocol.ErrMalformed)
+			require.Contains(t, err.Error(), "invalid team count "+n)
+		})
+	}
+}
+
+func TestDecodePlayersInvalid(t *testing.T) {
+	_, err := decodePlayers([]string{"1", "name", "2", "alice"})
+	require.Error(t, err)
+
+	_, err = decodePlayers([]string{"-1"})
+	require.Error(t, err)
+}
+
+func TestPlayers(t *testing.T) {
+	i := &Info{PlayerList: []Player{{Name: "alice", GUID: "EA_1", Kills: 10, Score: 1500, Ping: 35}}}
+	require.Equal(t, []protocol.Player{{Name: "alice", Score: 1500, Ping: 35}}, i.Players())
+}
@@ -0,0 +1,9 @@
+package frostbite
+
+import (
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+)
+
+func init() {
+	protocol.MustRegister("frostbite", newQueryer)
+}
@@ -0,0 +1,79 @@
+package frostbite
+
+import (
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+)
+
+// Info represents a full query response.
+type Info struct {
+	ServerName        string   `json:"server_name"`
+	PlayerCount       int64    `json:"player_count"`
+	MaxPlayerCount    int64    `json:"max_player_count"`
+	GameMode          string   `json:"game_mode"`
+	MapName           string   `json:"map"`
+	RoundsPlayed      int64    `json:"rounds_played"`
+	RoundsTotal       int64    `json:"rounds_total"`
+	TeamScores        []int64  `json:"team_scores"`
+	TargetScore       int64    `json:"target_score"`
+	OnlineState       string   `json:"online_state"`
+	Ranked            bool     `json:"ranked"`
+	PunkBuster        bool     `json:"punkbuster"`
+	Passworded        bool     `json:"passworded"`
+	ServerUpTime      int64    `json:"server_up_time"`
+	RoundTime         int64    `json:"round_time"`
+	GameAddress       string   `json:"game_address,omitempty"`
+	PunkBusterVersion string   `json:"punkbuster_version,omitempty"`
+	JoinQueueEnabled  bool     `json:"join_queue_enabled"`
+	Region            string   `json:"region,omitempty"`
+	ClosestPingSite   string   `json:"closest_ping_site,omitempty"`
+	Country           string   `json:"country,omitempty"`
+	PlayerList        []Player `json:"players,omitempty"`
+}
+
+// NumClients implements protocol.Responser.
+func (i *Info) NumClients() int64 {
+	return i.PlayerCount
+}
+
+// MaxClients implements protocol.Responser.
+func (i *Info) MaxClients() int64 {
+	return i.MaxPlayerCount
+}
+
+// Map implements protocol.Mapper.
+func (i *Info) Map() string {
+	return i.MapName
+}
+
+// Players implements protocol.PlayerLister.
+func (i *Info) Players() []protocol.Player {
+	players := make([]protocol.Player, len(i.PlayerList))
+	for j, p := range i.PlayerList {
+		players[j] = protocol.Player{
+			Name:  p.Name,
+			Score: p.Score,
+			Ping:  p.Ping,
+		}
+	}
+	return players
+}
+
+// Round returns the one based number of the current round.
+func (i *Info) Round() int64 {
+	return i.RoundsPlayed + 1
+}
+
+// Player represents a player in a listPlayers response.
+// Fields not reported by the game are left empty.
+type Player struct {
+	Name    string `json:"name"`
+	GUID    string `json:"guid,omitempty"`
+	TeamID  int64  `json:"team_id"`
+	SquadID int64  `json:"squad_id"`
+	Kills   int64  `json:"kills"`
+	Deaths  int64  `json:"deaths"`
+	Score   int64  `json:"score"`
+	Rank    int64  `json:"rank"`
+	Ping    int64  `json:"ping"`
+	Type    int64  `json:"type"`
+}
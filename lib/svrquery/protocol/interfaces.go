@@ -2,6 +2,8 @@ package protocol
 
 import (
 	"io"
+	"net"
+	"time"
 )
 
 // Queryer is an interface implemented by all svrquery protocols.
@@ -20,9 +22,64 @@ type Mapper interface {
 	Map() string
 }
 
+// Ruler represents something which can return the current server rules.
+type Ruler interface {
+	Rules() map[string]string
+}
+
+// Player represents the normalized details of a player.
+type Player struct {
+	Name  string `json:"name"`
+	Score int64  `json:"score"`
+	Ping  int64  `json:"ping"`
+}
+
+// PlayerLister represents something which can return the current players.
+type PlayerLister interface {
+	Players() []Player
+}
+
+// Networker represents a Queryer which requires a specific network for its transport
+// such as tcp for stream based protocols.
+type Networker interface {
+	Network() string
+}
+
 // Client is an interface which is implemented by types which can act a query transport.
 type Client interface {
 	io.ReadWriteCloser
 	Key() string
 	Address() string
 }
+
+// Argser represents a Client which provides protocol specific arguments.
+type Argser interface {
+	Args() map[string]interface{}
+}
+
+// RemoteAddrer represents a Client which can return the address of the
+// server it's connected to.
+type RemoteAddrer interface {
+	RemoteAddr() net.Addr
+}
+
+// TimeoutReader represents a Client which can read with a shorter timeout than its own.
+type TimeoutReader interface {
+	ReadTimeout(b []byte, timeout time.Duration) (int, error)
+}
+
+// Latencyer represents something which can return the exchanges of the last query.
+type Latencyer interface {
+	Exchanges() []Exchange
+}
+
+// ExchangeNamer represents a Client which can name the next exchange with a server.
+type ExchangeNamer interface {
+	NameExchange(name string)
+}
+
+// Pinger represents a Queryer which can perform the cheapest round trip
+// supported by its protocol, to measure latency.
+type Pinger interface {
+	Ping() error
+}
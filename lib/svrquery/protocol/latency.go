@@ -0,0 +1,48 @@
+package protocol
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Exchange is the timing of a request sent to a server and its response.
+type Exchange struct {
+	// Name identifies the exchange e.g. challenge, empty if not named.
+	Name string `json:"name,omitempty"`
+
+	// Sent is the time the request was sent.
+	Sent time.Time `json:"sent"`
+
+	// Received is the time the response was received, zero if it wasn't.
+	Received time.Time `json:"received"`
+
+	// TimedOut is true if a read timed out before the response was received.
+	TimedOut bool `json:"timed_out,omitempty"`
+}
+
+// RTT returns the round trip time of the exchange, zero if no response was received.
+func (e Exchange) RTT() time.Duration {
+	if e.Received.IsZero() {
+		return 0
+	}
+	return e.Received.Sub(e.Sent)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (e Exchange) MarshalJSON() ([]byte, error) {
+	type exchange Exchange
+	return json.Marshal(struct {
+		exchange
+		RTTMs float64 `json:"rtt_ms"`
+	}{
+		exchange: exchange(e),
+		RTTMs:    float64(e.RTT()) / float64(time.Millisecond),
+	})
+}
+
+// NameExchange names the next exchange of c if it implements ExchangeNamer.
+func NameExchange(c Client, name string) {
+	if n, ok := c.(ExchangeNamer); ok {
+		n.NameExchange(name)
+	}
+}
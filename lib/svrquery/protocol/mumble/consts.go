@@ -0,0 +1,12 @@
+package mumble
+
+const (
+	// PingRequest is the request type of a ping packet.
+	PingRequest = uint32(0)
+
+	// requestLength is the size of a ping request packet.
+	requestLength = 12
+
+	// responseLength is the size of a ping response packet.
+	responseLength = 24
+)
@@ -0,0 +1,3 @@
+// Package mumble provides the protocol implementation for the unauthenticated
+// UDP ping supported by Mumble voice servers.
+package mumble
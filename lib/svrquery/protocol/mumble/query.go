@@ -0,0 +1,55 @@
+package mumble
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/multiplay/go-svrquery/lib/svrquery/common"
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+)
+
+type queryer struct {
+	c   protocol.Client
+	now func() time.Time
+}
+
+func newQueryer(c protocol.Client) protocol.Queryer {
+	return &queryer{
+		c:   c,
+		now: time.Now,
+	}
+}
+
+// Query implements protocol.Queryer.
+func (q *queryer) Query() (protocol.Responser, error) {
+	ident := uint64(q.now().UnixNano())
+
+	b := make([]byte, responseLength)
+	binary.BigEndian.PutUint32(b, PingRequest)
+	binary.BigEndian.PutUint64(b[4:], ident)
+	if _, err := q.c.Write(b[:requestLength]); err != nil {
+		return nil, fmt.Errorf("query write: %w", err)
+	}
+
+	n, err := q.c.Read(b)
+	if err != nil {
+		return nil, fmt.Errorf("query read: %w", err)
+	} else if n < responseLength {
+		return nil, fmt.Errorf("%w: packet too short (len: %d)", protocol.ErrMalformed, n)
+	}
+	var resp pingResponse
+	r := common.NewBinaryReader(b[:n], binary.BigEndian)
+	if err = r.Read(&resp); err != nil {
+		return nil, fmt.Errorf("%w: %w", protocol.ErrMalformed, err)
+	} else if resp.Ident != ident {
+		return nil, fmt.Errorf("%w: unexpected ident %x (expected %x)", protocol.ErrChallenge, resp.Ident, ident)
+	}
+
+	return &Info{
+		Version:   Version(resp.Version),
+		Users:     resp.Users,
+		MaxUsers:  resp.MaxUsers,
+		Bandwidth: resp.Bandwidth,
+	}, nil
+}
@@ -0,0 +1,68 @@
+package mumble
+
+import (
+	"testing"
+	"time"
+
+	"github.com/multiplay/go-svrquery/lib/svrquery/clienttest"
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testDir = "testdata"
+)
+
+func TestQuery(t *testing.T) {
+	sent := time.Unix(1600000000, 0)
+
+	cases := []struct {
+		name     string
+		response string
+		expected *Info
+		err      error
+	}{
+		{
+			name:     "ping",
+			response: "ping_response",
+			expected: &Info{
+				Version:   Version(0x00010204),
+				Users:     5,
+				MaxUsers:  100,
+				Bandwidth: 558000,
+			},
+		},
+		{
+			name:     "invalid_ident",
+			response: "ping_invalid_response",
+			err:      protocol.ErrChallenge,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := clienttest.LoadData(t, testDir, "ping_request")
+			resp := clienttest.LoadData(t, testDir, tc.response)
+
+			mc := &clienttest.MockClient{}
+			mc.On("Write", req).Return(len(req), nil)
+			mc.On("Read", mock.AnythingOfType("[]uint8")).Return(resp, nil)
+
+			q := &queryer{
+				c:   mc,
+				now: func() time.Time { return sent },
+			}
+
+			i, err := q.Query()
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, i)
+			require.Equal(t, "1.2.4", tc.expected.Version.String())
+			mc.AssertExpectations(t)
+		})
+	}
+}
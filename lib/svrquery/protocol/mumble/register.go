@@ -0,0 +1,9 @@
+package mumble
+
+import (
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+)
+
+func init() {
+	protocol.MustRegister("mumble", newQueryer)
+}
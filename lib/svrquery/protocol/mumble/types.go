@@ -0,0 +1,60 @@
+package mumble
+
+import (
+	"fmt"
+)
+
+// Info represents a ping response.
+type Info struct {
+	Version   Version `json:"version"`
+	Users     uint32  `json:"users"`
+	MaxUsers  uint32  `json:"max_users"`
+	Bandwidth uint32  `json:"bandwidth"`
+}
+
+// NumClients implements protocol.Responser.
+func (i *Info) NumClients() int64 {
+	return int64(i.Users)
+}
+
+// MaxClients implements protocol.Responser.
+func (i *Info) MaxClients() int64 {
+	return int64(i.MaxUsers)
+}
+
+// Version represents a server version encoded as 0x00MMmmpp.
+type Version uint32
+
+// Major returns the major component of the version.
+func (v Version) Major() uint16 {
+	return uint16(v >> 16)
+}
+
+// Minor returns the minor component of the version.
+func (v Version) Minor() byte {
+	return byte(v >> 8)
+}
+
+// Patch returns the patch component of the version.
+func (v Version) Patch() byte {
+	return byte(v)
+}
+
+// String implements fmt.Stringer.
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major(), v.Minor(), v.Patch())
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (v Version) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+// pingResponse is the wire format of a ping response.
+type pingResponse struct {
+	Version   uint32
+	Ident     uint64
+	Users     uint32
+	MaxUsers  uint32
+	Bandwidth uint32
+}
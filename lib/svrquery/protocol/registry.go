@@ -2,6 +2,7 @@ package protocol
 
 import (
 	"fmt"
+	"sort"
 )
 
 // Creator is a function which returns a Queryer.
@@ -34,3 +35,13 @@ func Supported(name string) bool {
 	_, ok := registry[name]
 	return ok
 }
+
+// Names returns the names of the registered protocols in order.
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,45 @@
+package samp
+
+// Request opcodes.
+const (
+	// InfoRequest is the opcode of a server information request.
+	InfoRequest = byte('i')
+
+	// RulesRequest is the opcode of a rules request.
+	RulesRequest = byte('r')
+
+	// ClientListRequest is the opcode of a basic player list request.
+	ClientListRequest = byte('c')
+
+	// DetailedPlayersRequest is the opcode of a detailed player list request.
+	DetailedPlayersRequest = byte('d')
+
+	// PingRequest is the opcode of a ping request.
+	PingRequest = byte('p')
+)
+
+const (
+	// MaxListedPlayers is the maximum number of players for which a server
+	// will respond to player list requests.
+	MaxListedPlayers = 100
+
+	// headerLength is the length of the header which prefixes requests and responses.
+	headerLength = 11
+
+	// packetSize is the maximum size of a response packet.
+	packetSize = 4096
+)
+
+var (
+	// magic is the prefix of all packets.
+	magic = []byte("SAMP")
+
+	// requestNames are the exchange names of the request opcodes.
+	requestNames = map[byte]string{
+		InfoRequest:            "info",
+		RulesRequest:           "rules",
+		ClientListRequest:      "clients",
+		DetailedPlayersRequest: "players",
+		PingRequest:            "ping",
+	}
+)
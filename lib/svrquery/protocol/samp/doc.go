@@ -0,0 +1,3 @@
+// Package samp provides the protocol implementation for the San Andreas
+// Multiplayer query protocol, which is also supported by open.mp.
+package samp
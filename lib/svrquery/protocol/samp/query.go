@@ -0,0 +1,317 @@
+package samp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/multiplay/go-svrquery/lib/svrquery/common"
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+)
+
+type queryer struct {
+	c      protocol.Client
+	now    func() time.Time
+	header []byte
+}
+
+func newQueryer(c protocol.Client) protocol.Queryer {
+	return &queryer{
+		c:   c,
+		now: time.Now,
+	}
+}
+
+// Query implements protocol.Queryer.
+func (q *queryer) Query() (protocol.Responser, error) {
+	if err := q.init(); err != nil {
+		return nil, err
+	}
+
+	i := &Info{}
+	if err := q.ping(); err != nil {
+		return nil, err
+	} else if err = q.info(i); err != nil {
+		return nil, err
+	} else if err = q.rules(i); err != nil {
+		return nil, err
+	}
+
+	if i.NumPlayers == 0 || i.NumPlayers > MaxListedPlayers {
+		// Servers don't respond to player list requests above the limit.
+		return i, nil
+	}
+
+	err := q.detailedPlayers(i)
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		// Detailed player lists can be disabled so fallback to the basic list.
+		err = q.clientList(i)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return i, nil
+}
+
+// Ping implements protocol.Pinger using only the ping exchange.
+func (q *queryer) Ping() error {
+	if err := q.init(); err != nil {
+		return err
+	}
+	return q.ping()
+}
+
+// init creates the request header if required.
+func (q *queryer) init() error {
+	if q.header != nil {
+		return nil
+	}
+
+	var addr net.Addr
+	if ra, ok := q.c.(protocol.RemoteAddrer); ok {
+		addr = ra.RemoteAddr()
+	} else {
+		ua, err := net.ResolveUDPAddr("udp", q.c.Address())
+		if err != nil {
+			return fmt.Errorf("%w: %w", protocol.ErrUnreachable, err)
+		}
+		addr = ua
+	}
+
+	h, err := newHeader(addr)
+	if err != nil {
+		return err
+	}
+	q.header = h
+	return nil
+}
+
+// newHeader returns the request header for the server at addr, which embeds
+// its IPv4 address and port. The address of IPv6 servers is left unset, as
+// the header has no room for it.
+func newHeader(addr net.Addr) ([]byte, error) {
+	ua, ok := addr.(*net.UDPAddr)
+	if !ok {
+		return nil, fmt.Errorf("address %q is not UDP", addr)
+	}
+
+	ip := ua.IP.To4()
+	if ip == nil {
+		ip = net.IPv4zero.To4()
+	}
+
+	h := make([]byte, 0, headerLength)
+	h = append(h, magic...)
+	h = append(h, ip...)
+	return binary.LittleEndian.AppendUint16(h, uint16(ua.Port)), nil
+}
+
+// request sends a request with the given opcode and payload, returning a
+// reader positioned after the echoed header of the response.
+func (q *queryer) request(opcode byte, payload ...byte) (*common.BinaryReader, error) {
+	req := make([]byte, 0, headerLength+len(payload))
+	req = append(req, q.header...)
+	req = append(req, opcode)
+	req = append(req, payload...)
+	protocol.NameExchange(q.c, requestNames[opcode])
+	if _, err := q.c.Write(req); err != nil {
+		return nil, fmt.Errorf("query write: %w", err)
+	}
+
+	b := make([]byte, packetSize)
+	n, err := q.c.Read(b)
+	if err != nil {
+		return nil, fmt.Errorf("query read: %w", err)
+	} else if n < len(req) {
+		return nil, fmt.Errorf("%w: packet too short (len: %d)", protocol.ErrMalformed, n)
+	} else if !bytes.Equal(b[:len(req)-len(payload)], req[:len(req)-len(payload)]) {
+		return nil, fmt.Errorf("%w: unexpected header %x", protocol.ErrMalformed, b[:headerLength])
+	}
+
+	return common.NewBinaryReader(b[headerLength:n], binary.LittleEndian), nil
+}
+
+// ping sends a ping request, whose round trip time is recorded by the client.
+func (q *queryer) ping() error {
+	payload := binary.LittleEndian.AppendUint32(nil, uint32(q.now().UnixNano()))
+	r, err := q.request(PingRequest, payload...)
+	if err != nil {
+		return err
+	}
+
+	echo := make([]byte, len(payload))
+	if err = r.Read(echo); err != nil {
+		return fmt.Errorf("%w: ping: %w", protocol.ErrMalformed, err)
+	} else if !bytes.Equal(echo, payload) {
+		return fmt.Errorf("%w: unexpected ping payload %x (expected %x)", protocol.ErrChallenge, echo, payload)
+	}
+	return nil
+}
+
+// info requests the server information.
+func (q *queryer) info(i *Info) error {
+	r, err := q.request(InfoRequest)
+	if err != nil {
+		return err
+	}
+
+	if err = decodeInfo(r, i); err != nil {
+		return fmt.Errorf("%w: info: %w", protocol.ErrMalformed, err)
+	}
+	return nil
+}
+
+// decodeInfo decodes a server information response.
+func decodeInfo(r *common.BinaryReader, i *Info) (err error) {
+	var passworded byte
+	if err = r.Read(&passworded); err != nil {
+		return err
+	} else if err = r.Read(&i.NumPlayers); err != nil {
+		return err
+	} else if err = r.Read(&i.MaxPlayers); err != nil {
+		return err
+	} else if i.Hostname, err = readString32(r); err != nil {
+		return err
+	} else if i.GameMode, err = readString32(r); err != nil {
+		return err
+	} else if i.Language, err = readString32(r); err != nil {
+		return err
+	}
+	i.Passworded = passworded != 0
+	return nil
+}
+
+// rules requests the server rules.
+func (q *queryer) rules(i *Info) error {
+	r, err := q.request(RulesRequest)
+	if err != nil {
+		return err
+	}
+
+	if err = decodeRules(r, i); err != nil {
+		return fmt.Errorf("%w: rules: %w", protocol.ErrMalformed, err)
+	}
+	return nil
+}
+
+// decodeRules decodes a rules response.
+func decodeRules(r *common.BinaryReader, i *Info) (err error) {
+	var count uint16
+	if err = r.Read(&count); err != nil {
+		return err
+	}
+
+	i.ServerRules = make(map[string]string, count)
+	for j := 0; j < int(count); j++ {
+		k, err := readString8(r)
+		if err != nil {
+			return err
+		}
+		if i.ServerRules[k], err = readString8(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clientList requests the basic player list.
+func (q *queryer) clientList(i *Info) error {
+	r, err := q.request(ClientListRequest)
+	if err != nil {
+		return err
+	}
+
+	if err = decodeClientList(r, i); err != nil {
+		return fmt.Errorf("%w: client list: %w", protocol.ErrMalformed, err)
+	}
+	return nil
+}
+
+// decodeClientList decodes a basic player list response.
+func decodeClientList(r *common.BinaryReader, i *Info) (err error) {
+	var count uint16
+	if err = r.Read(&count); err != nil {
+		return err
+	}
+
+	i.PlayerList = make([]Player, count)
+	for j := range i.PlayerList {
+		p := &i.PlayerList[j]
+		if p.Name, err = readString8(r); err != nil {
+			return err
+		} else if err = r.Read(&p.Score); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// detailedPlayers requests the detailed player list.
+func (q *queryer) detailedPlayers(i *Info) error {
+	r, err := q.request(DetailedPlayersRequest)
+	if err != nil {
+		return err
+	}
+
+	if err = decodeDetailedPlayers(r, i); err != nil {
+		return fmt.Errorf("%w: detailed players: %w", protocol.ErrMalformed, err)
+	}
+	return nil
+}
+
+// decodeDetailedPlayers decodes a detailed player list response.
+func decodeDetailedPlayers(r *common.BinaryReader, i *Info) (err error) {
+	var count uint16
+	if err = r.Read(&count); err != nil {
+		return err
+	}
+
+	i.PlayerList = make([]Player, count)
+	for j := range i.PlayerList {
+		p := &i.PlayerList[j]
+		if err = r.Read(&p.ID); err != nil {
+			return err
+		} else if p.Name, err = readString8(r); err != nil {
+			return err
+		} else if err = r.Read(&p.Score); err != nil {
+			return err
+		} else if err = r.Read(&p.Ping); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readString8 reads a string prefixed by a byte length.
+func readString8(r *common.BinaryReader) (string, error) {
+	var l byte
+	if err := r.Read(&l); err != nil {
+		return "", err
+	}
+	return readString(r, int(l))
+}
+
+// readString32 reads a string prefixed by a uint32 length.
+func readString32(r *common.BinaryReader) (string, error) {
+	var l uint32
+	if err := r.Read(&l); err != nil {
+		return "", err
+	} else if l > packetSize {
+		return "", fmt.Errorf("string length %d exceeds packet size", l)
+	}
+	return readString(r, int(l))
+}
+
+// readString reads a string of length l.
+func readString(r *common.BinaryReader, l int) (string, error) {
+	b := make([]byte, l)
+	if err := r.Read(b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,157 @@
+package samp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/multiplay/go-svrquery/lib/svrquery/clienttest"
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testDir = "testdata"
+)
+
+// timeoutError is a net.Error which represents a read timeout.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+var _ net.Error = timeoutError{}
+
+func TestQuery(t *testing.T) {
+	sent := time.Unix(1600000000, 0)
+
+	base := Info{
+		Passworded: true,
+		NumPlayers: 2,
+		MaxPlayers: 50,
+		Hostname:   "My SA-MP Server",
+		GameMode:   "Freeroam",
+		Language:   "",
+		ServerRules: map[string]string{
+			"lagcomp": "On",
+			"mapname": "San Andreas",
+			"version": "0.3.7-R2",
+		},
+	}
+
+	detailed := base
+	detailed.PlayerList = []Player{
+		{ID: 0, Name: "alice", Score: 15, Ping: 40},
+		{ID: 3, Name: "bob", Score: -2, Ping: 90},
+	}
+
+	clients := base
+	clients.PlayerList = []Player{
+		{Name: "alice", Score: 15},
+		{Name: "bob", Score: -2},
+	}
+
+	cases := []struct {
+		name      string
+		exchanges []string
+		timeout   string
+		expected  *Info
+	}{
+		{
+			name:      "detailed",
+			exchanges: []string{"ping", "info", "rules", "detailed"},
+			expected:  &detailed,
+		},
+		{
+			name:      "clients_fallback",
+			exchanges: []string{"ping", "info", "rules", "detailed", "clients"},
+			timeout:   "detailed",
+			expected:  &clients,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mc := &clienttest.MockClient{}
+			mc.On("Address").Return("127.0.0.1:7777")
+			for _, e := range tc.exchanges {
+				req := clienttest.LoadData(t, testDir, e+"_request")
+				mc.On("Write", req).Return(len(req), nil).Once()
+				if e == tc.timeout {
+					mc.On("Read", mock.AnythingOfType("[]uint8")).Return([]byte{}, timeoutError{}).Once()
+					continue
+				}
+				resp := clienttest.LoadData(t, testDir, e+"_response")
+				mc.On("Read", mock.AnythingOfType("[]uint8")).Return(resp, nil).Once()
+			}
+
+			q := &queryer{
+				c:   mc,
+				now: func() time.Time { return sent },
+			}
+
+			i, err := q.Query()
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, i)
+			require.Equal(t, "San Andreas", i.(*Info).Map())
+			mc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestQueryUnexpectedHeader(t *testing.T) {
+	mc := &clienttest.MockClient{}
+	mc.On("Address").Return("127.0.0.1:7777")
+	req := clienttest.LoadData(t, testDir, "info_request")
+	resp := clienttest.LoadData(t, testDir, "info_invalid_response")
+	mc.On("Write", req).Return(len(req), nil)
+	mc.On("Read", mock.AnythingOfType("[]uint8")).Return(resp, nil)
+
+	q := newQueryer(mc).(*queryer)
+	require.NoError(t, q.init())
+
+	require.ErrorIs(t, q.info(&Info{}), protocol.ErrMalformed)
+}
+
+func TestNewHeader(t *testing.T) {
+	h, err := newHeader(&net.UDPAddr{IP: net.IPv4(10, 1, 2, 3), Port: 7778})
+	require.NoError(t, err)
+	require.Equal(t, []byte{'S', 'A', 'M', 'P', 10, 1, 2, 3, 0x62, 0x1e}, h)
+
+	h, err = newHeader(&net.UDPAddr{IP: net.IPv6loopback, Port: 7778})
+	require.NoError(t, err)
+	require.Equal(t, []byte{'S', 'A', 'M', 'P', 0, 0, 0, 0, 0x62, 0x1e}, h)
+
+	_, err = newHeader(&net.TCPAddr{IP: net.IPv4(10, 1, 2, 3), Port: 7778})
+	require.Error(t, err)
+}
+
+// remoteAddrClient is a MockClient connected to addr.
+type remoteAddrClient struct {
+	clienttest.MockClient
+	addr net.Addr
+}
+
+func (c *remoteAddrClient) RemoteAddr() net.Addr {
+	return c.addr
+}
+
+func TestInitRemoteAddr(t *testing.T) {
+	// The dialed address is used rather than resolving the address again.
+	c := &remoteAddrClient{addr: &net.UDPAddr{IP: net.IPv4(10, 1, 2, 3), Port: 7778}}
+	q := newQueryer(c).(*queryer)
+	require.NoError(t, q.init())
+	require.Equal(t, []byte{'S', 'A', 'M', 'P', 10, 1, 2, 3, 0x62, 0x1e}, q.header)
+	c.AssertNotCalled(t, "Address")
+}
+
+func TestRulesPlayers(t *testing.T) {
+	i := &Info{
+		ServerRules: map[string]string{"mapname": "San Andreas"},
+		PlayerList:  []Player{{ID: 1, Name: "alice", Score: -2, Ping: 50}},
+	}
+	require.Equal(t, map[string]string{"mapname": "San Andreas"}, i.Rules())
+	require.Equal(t, []protocol.Player{{Name: "alice", Score: -2, Ping: 50}}, i.Players())
+}
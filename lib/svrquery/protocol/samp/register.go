@@ -0,0 +1,9 @@
+package samp
+
+import (
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+)
+
+func init() {
+	protocol.MustRegister("samp", newQueryer)
+}
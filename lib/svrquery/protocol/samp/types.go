@@ -0,0 +1,60 @@
+package samp
+
+import (
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+)
+
+// Info represents a full query response.
+type Info struct {
+	Passworded  bool              `json:"passworded"`
+	NumPlayers  uint16            `json:"players"`
+	MaxPlayers  uint16            `json:"max_players"`
+	Hostname    string            `json:"hostname"`
+	GameMode    string            `json:"game_mode"`
+	Language    string            `json:"language"`
+	ServerRules map[string]string `json:"rules,omitempty"`
+	PlayerList  []Player          `json:"player_list,omitempty"`
+}
+
+// NumClients implements protocol.Responser.
+func (i *Info) NumClients() int64 {
+	return int64(i.NumPlayers)
+}
+
+// MaxClients implements protocol.Responser.
+func (i *Info) MaxClients() int64 {
+	return int64(i.MaxPlayers)
+}
+
+// Map implements protocol.Mapper.
+// SA-MP servers report the map name as the mapname rule.
+func (i *Info) Map() string {
+	return i.ServerRules["mapname"]
+}
+
+// Rules implements protocol.Ruler.
+func (i *Info) Rules() map[string]string {
+	return i.ServerRules
+}
+
+// Players implements protocol.PlayerLister.
+func (i *Info) Players() []protocol.Player {
+	players := make([]protocol.Player, len(i.PlayerList))
+	for j, p := range i.PlayerList {
+		players[j] = protocol.Player{
+			Name:  p.Name,
+			Score: int64(p.Score),
+			Ping:  int64(p.Ping),
+		}
+	}
+	return players
+}
+
+// Player represents a player in a query response.
+// ID and Ping are only populated by detailed player list responses.
+type Player struct {
+	ID    byte   `json:"id"`
+	Name  string `json:"name"`
+	Score int32  `json:"score"`
+	Ping  uint32 `json:"ping"`
+}
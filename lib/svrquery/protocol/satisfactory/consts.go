@@ -0,0 +1,71 @@
+package satisfactory
+
+const (
+	// ProtocolMagic is the magic which prefixes all packets.
+	ProtocolMagic = uint16(0xF6D5)
+
+	// ProtocolVersion is the protocol version this client uses.
+	ProtocolVersion = byte(1)
+
+	// Terminator is the byte which terminates all packets.
+	Terminator = byte(0x01)
+
+	// minLength is the smallest packet we can expect, a header, cookie and terminator.
+	minLength = 13
+
+	// packetSize is the maximum size of a response packet.
+	packetSize = 1400
+)
+
+// Message types.
+const (
+	// PollServerState is the message type of a server state request.
+	PollServerState byte = iota
+
+	// ServerStateResponse is the message type of a server state response.
+	ServerStateResponse
+)
+
+// ServerState is the state of the server.
+type ServerState byte
+
+// Server states.
+const (
+	// Offline indicates that the server is offline.
+	Offline ServerState = iota
+
+	// Idle indicates that the server is running but no game is loaded.
+	Idle
+
+	// Loading indicates that the server is loading a game.
+	Loading
+
+	// Playing indicates that the server is running a game.
+	Playing
+)
+
+// String implements fmt.Stringer.
+func (s ServerState) String() string {
+	switch s {
+	case Offline:
+		return "offline"
+	case Idle:
+		return "idle"
+	case Loading:
+		return "loading"
+	case Playing:
+		return "playing"
+	}
+	return "unknown"
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s ServerState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Server flags.
+const (
+	// FlagModded indicates that the server is running mods.
+	FlagModded = uint64(1) << iota
+)
@@ -0,0 +1,3 @@
+// Package satisfactory provides the protocol implementation for the
+// Satisfactory dedicated server Lightweight Query API.
+package satisfactory
@@ -0,0 +1,113 @@
+package satisfactory
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+
+	"github.com/multiplay/go-svrquery/lib/svrquery/common"
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+)
+
+type queryer struct {
+	c         protocol.Client
+	newCookie func() uint64
+	cookie    uint64
+}
+
+func newQueryer(c protocol.Client) protocol.Queryer {
+	return &queryer{
+		c:         c,
+		newCookie: rand.Uint64,
+	}
+}
+
+// Query implements protocol.Queryer.
+func (q *queryer) Query() (protocol.Responser, error) {
+	q.cookie = q.newCookie()
+
+	b := make([]byte, 0, packetSize)
+	b = binary.LittleEndian.AppendUint16(b, ProtocolMagic)
+	b = append(b, PollServerState, ProtocolVersion)
+	b = binary.LittleEndian.AppendUint64(b, q.cookie)
+	b = append(b, Terminator)
+	if _, err := q.c.Write(b); err != nil {
+		return nil, fmt.Errorf("query write: %w", err)
+	}
+
+	b = b[:packetSize]
+	n, err := q.c.Read(b)
+	if err != nil {
+		return nil, fmt.Errorf("query read: %w", err)
+	} else if n < minLength {
+		return nil, fmt.Errorf("%w: packet too short (len: %d)", protocol.ErrMalformed, n)
+	} else if b[n-1] != Terminator {
+		return nil, fmt.Errorf("%w: unexpected terminator %x", protocol.ErrMalformed, b[n-1])
+	}
+
+	r := common.NewBinaryReader(b[:n-1], binary.LittleEndian)
+	var h header
+	if err = r.Read(&h); err != nil {
+		return nil, fmt.Errorf("%w: %w", protocol.ErrMalformed, err)
+	} else if h.Magic != ProtocolMagic {
+		return nil, fmt.Errorf("%w: unexpected magic %x", protocol.ErrMalformed, h.Magic)
+	} else if h.MessageType != ServerStateResponse {
+		return nil, fmt.Errorf("%w: unexpected message type %x", protocol.ErrMalformed, h.MessageType)
+	} else if h.ProtocolVersion != ProtocolVersion {
+		return nil, fmt.Errorf("%w: protocol version %d", protocol.ErrUnsupportedVersion, h.ProtocolVersion)
+	}
+
+	if err = q.validateCookie(r); err != nil {
+		return nil, err
+	}
+
+	i, err := q.readServerState(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", protocol.ErrMalformed, err)
+	}
+	return i, nil
+}
+
+// validateCookie reads and validates the cookie of a response against our current cookie.
+func (q *queryer) validateCookie(r *common.BinaryReader) error {
+	var cookie uint64
+	if err := r.Read(&cookie); err != nil {
+		return fmt.Errorf("%w: %w", protocol.ErrMalformed, err)
+	} else if cookie != q.cookie {
+		return fmt.Errorf("%w: was expecting 0x%016x for cookie, got 0x%016x", protocol.ErrChallenge, q.cookie, cookie)
+	}
+	return nil
+}
+
+// readServerState decodes the body of a server state response.
+func (q *queryer) readServerState(r *common.BinaryReader) (*Info, error) {
+	i := &Info{}
+	if err := r.Read(&i.State); err != nil {
+		return nil, err
+	} else if err = r.Read(&i.NetCL); err != nil {
+		return nil, err
+	} else if err = r.Read(&i.Flags); err != nil {
+		return nil, err
+	}
+
+	var numSubStates byte
+	if err := r.Read(&numSubStates); err != nil {
+		return nil, err
+	}
+	i.SubStates = make([]SubState, numSubStates)
+	if err := r.Read(i.SubStates); err != nil {
+		return nil, err
+	}
+
+	var l uint16
+	if err := r.Read(&l); err != nil {
+		return nil, err
+	}
+	name := make([]byte, l)
+	if err := r.Read(name); err != nil {
+		return nil, err
+	}
+	i.ServerName = string(name)
+
+	return i, nil
+}
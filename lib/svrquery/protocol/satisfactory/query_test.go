@@ -0,0 +1,75 @@
+package satisfactory
+
+import (
+	"testing"
+
+	"github.com/multiplay/go-svrquery/lib/svrquery/clienttest"
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testDir    = "testdata"
+	testCookie = uint64(0x0102030405060708)
+)
+
+func TestQuery(t *testing.T) {
+	cases := []struct {
+		name     string
+		response string
+		expected *Info
+		err      error
+	}{
+		{
+			name:     "state",
+			response: "state_response",
+			expected: &Info{
+				State: Playing,
+				NetCL: 365306,
+				Flags: FlagModded,
+				SubStates: []SubState{
+					{ID: 0, Version: 12},
+					{ID: 1, Version: 3},
+					{ID: 3, Version: 40},
+				},
+				ServerName: "My Factory",
+			},
+		},
+		{
+			name:     "invalid_cookie",
+			response: "state_invalid_cookie_response",
+			err:      protocol.ErrChallenge,
+		},
+		{
+			name:     "invalid_magic",
+			response: "state_invalid_magic_response",
+			err:      protocol.ErrMalformed,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := clienttest.LoadData(t, testDir, "state_request")
+			resp := clienttest.LoadData(t, testDir, tc.response)
+
+			mc := &clienttest.MockClient{}
+			mc.On("Write", req).Return(len(req), nil)
+			mc.On("Read", mock.AnythingOfType("[]uint8")).Return(resp, nil)
+
+			q := &queryer{
+				c:         mc,
+				newCookie: func() uint64 { return testCookie },
+			}
+			i, err := q.Query()
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, i)
+			require.True(t, i.(*Info).Modded())
+			mc.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,9 @@
+package satisfactory
+
+import (
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+)
+
+func init() {
+	protocol.MustRegister("satisfactory", newQueryer)
+}
@@ -0,0 +1,41 @@
+package satisfactory
+
+// Info represents a server state response.
+type Info struct {
+	State      ServerState `json:"state"`
+	NetCL      uint32      `json:"net_cl"`
+	Flags      uint64      `json:"flags"`
+	SubStates  []SubState  `json:"sub_states"`
+	ServerName string      `json:"server_name"`
+}
+
+// NumClients implements protocol.Responser.
+// The player count is not reported by the Lightweight Query API.
+func (i *Info) NumClients() int64 {
+	return 0
+}
+
+// MaxClients implements protocol.Responser.
+// The player limit is not reported by the Lightweight Query API.
+func (i *Info) MaxClients() int64 {
+	return 0
+}
+
+// Modded returns true if the server is running mods.
+func (i *Info) Modded() bool {
+	return i.Flags&FlagModded != 0
+}
+
+// SubState represents the version of a server sub state, which changes when
+// the sub state changes so clients know when to fetch it via the HTTPS API.
+type SubState struct {
+	ID      byte   `json:"id"`
+	Version uint16 `json:"version"`
+}
+
+// header is the wire format of a packet header.
+type header struct {
+	Magic           uint16
+	MessageType     byte
+	ProtocolVersion byte
+}
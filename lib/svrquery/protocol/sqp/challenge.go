@@ -2,6 +2,9 @@ package sqp
 
 import (
 	"bytes"
+	"fmt"
+
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
 )
 
 // Challenge sends a challenge request and validates a response
@@ -34,6 +37,7 @@ func (q *queryer) sendChallenge() error {
 		return err
 	}
 
+	protocol.NameExchange(q.c, "challenge")
 	_, err := q.c.Write(pkt.Bytes())
 	return err
 }
@@ -48,7 +52,7 @@ func (q *queryer) validateChallenge() error {
 	if id, err := q.readChallenge(); err != nil {
 		return err
 	} else if id != q.challengeID {
-		return NewErrMalformedPacketf("was expecting 0x%04x for challengeID, got 0x%04x", q.challengeID, id)
+		return fmt.Errorf("%w: %w", protocol.ErrChallenge, NewErrMalformedPacketf("was expecting 0x%04x for challengeID, got 0x%04x", q.challengeID, id))
 	}
 	return nil
 }
@@ -8,6 +8,11 @@ const (
 	// Version is the query protocol version this client uses.
 	Version = uint16(1)
 
+	// ChunksArg is the client argument which sets the requested chunks.
+	// The value is either a byte or a string of chunk names separated by "+"
+	// e.g. "info+metrics", see chunkNames.
+	ChunksArg = "chunks"
+
 	// MaxMetrics is the maximum number of metrics supported in a request.
 	MaxMetrics = byte(25)
 )
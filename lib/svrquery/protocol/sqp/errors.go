@@ -3,6 +3,8 @@ package sqp
 import (
 	"errors"
 	"fmt"
+
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
 )
 
 // ErrMalformedPacket is raised when a malformed packet is encountered
@@ -12,6 +14,11 @@ func (e ErrMalformedPacket) Error() string {
 	return fmt.Sprintf("malformed packet: %v", string(e))
 }
 
+// Is returns true if target is protocol.ErrMalformed.
+func (e ErrMalformedPacket) Is(target error) bool {
+	return target == protocol.ErrMalformed
+}
+
 // NewErrMalformedPacketf makes a new ErrMalformedPacket with the formatted string
 func NewErrMalformedPacketf(format string, args ...interface{}) ErrMalformedPacket {
 	return ErrMalformedPacket(fmt.Sprintf(format, args...))
@@ -32,3 +39,8 @@ type ErrUnknownDataType DataType
 func (e ErrUnknownDataType) Error() string {
 	return fmt.Sprintf("unknown datatype %v", string(e))
 }
+
+// Is returns true if target is protocol.ErrMalformed.
+func (e ErrUnknownDataType) Is(target error) bool {
+	return target == protocol.ErrMalformed
+}
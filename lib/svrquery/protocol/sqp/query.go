@@ -4,8 +4,10 @@ import (
 	"bufio"
 	"bytes"
 	"encoding/binary"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"strings"
 
 	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
 )
@@ -16,10 +18,49 @@ type queryer struct {
 	reader          *packetReader
 	challengeID     uint32
 	requestedChunks byte
+	err             error
+}
+
+// chunkNames maps the names which can be used in ChunksArg to chunks.
+var chunkNames = map[string]byte{
+	"info":    ServerInfo,
+	"rules":   ServerRules,
+	"players": PlayerInfo,
+	"teams":   TeamInfo,
+	"metrics": Metrics,
 }
 
 func newCreator(c protocol.Client) protocol.Queryer {
-	return newQueryer(ServerInfo, DefaultMaxPacketSize, c)
+	chunks := ServerInfo
+	var err error
+	if a, ok := c.(protocol.Argser); ok {
+		if v, ok := a.Args()[ChunksArg]; ok {
+			chunks, err = parseChunks(v)
+		}
+	}
+
+	q := newQueryer(chunks, DefaultMaxPacketSize, c)
+	q.err = err
+	return q
+}
+
+// parseChunks returns the chunks requested by a ChunksArg value.
+func parseChunks(v interface{}) (byte, error) {
+	switch v := v.(type) {
+	case byte:
+		return v, nil
+	case string:
+		var chunks byte
+		for _, name := range strings.Split(v, "+") {
+			c, ok := chunkNames[strings.ToLower(strings.TrimSpace(name))]
+			if !ok {
+				return 0, fmt.Errorf("unknown chunk %q", name)
+			}
+			chunks |= c
+		}
+		return chunks, nil
+	}
+	return 0, fmt.Errorf("invalid %s type %T", ChunksArg, v)
 }
 
 func newQueryer(requestedChunks byte, maxPktSize int, c protocol.Client) *queryer {
@@ -33,6 +74,10 @@ func newQueryer(requestedChunks byte, maxPktSize int, c protocol.Client) *querye
 
 // Query implements protocol.Queryer.
 func (q *queryer) Query() (protocol.Responser, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+
 	if err := q.sendQuery(q.requestedChunks); err != nil {
 		return nil, err
 	}
@@ -40,6 +85,11 @@ func (q *queryer) Query() (protocol.Responser, error) {
 	return q.readQuery(q.requestedChunks)
 }
 
+// Ping implements protocol.Pinger using only the challenge exchange.
+func (q *queryer) Ping() error {
+	return q.Challenge()
+}
+
 func (q *queryer) sendQuery(requestedChunks byte) error {
 	// Each query requires a new challenge.
 	if err := q.Challenge(); err != nil {
@@ -63,6 +113,7 @@ func (q *queryer) sendQuery(requestedChunks byte) error {
 		return err
 	}
 
+	protocol.NameExchange(q.c, "query")
 	_, err := q.c.Write(pkt.Bytes())
 	return err
 }
@@ -106,12 +157,15 @@ func (q *queryer) readQueryHeader() (uint16, byte, byte, uint16, error) {
 }
 
 func (q *queryer) readQuery(requestedChunks byte) (*QueryResponse, error) {
-	// Multi-packet streams are not supported.
-	version, _, _, pktLen, err := q.readQueryHeader()
+	version, curPkt, lastPkt, pktLen, err := q.readQueryHeader()
 	if err != nil {
 		return nil, err
 	}
 
+	if lastPkt > 0 {
+		return q.readQueryMultiPacket(version, requestedChunks, curPkt, lastPkt, pktLen)
+	}
+
 	// If the header says the body is empty, we should just return now
 	if pktLen == 0 {
 		return &QueryResponse{Version: version, Address: q.c.Address()}, nil
@@ -120,6 +174,50 @@ func (q *queryer) readQuery(requestedChunks byte) (*QueryResponse, error) {
 	return q.readQuerySinglePacket(q.reader, version, requestedChunks, uint32(pktLen))
 }
 
+// readQueryMultiPacket reads the payloads of a response split across packets,
+// which can arrive in any order, and decodes the reassembled payload.
+func (q *queryer) readQueryMultiPacket(version uint16, requestedChunks, curPkt, lastPkt byte, pktLen uint16) (*QueryResponse, error) {
+	payloads := make([][]byte, int(lastPkt)+1)
+	var received, size int
+	for {
+		b := make([]byte, pktLen)
+		if _, err := io.ReadFull(q.reader, b); err != nil {
+			return nil, err
+		}
+
+		// Duplicate packets are ignored.
+		if payloads[curPkt] == nil {
+			payloads[curPkt] = b
+			received++
+			size += len(b)
+		}
+
+		if received == len(payloads) {
+			break
+		}
+
+		var v uint16
+		var last byte
+		var err error
+		if v, curPkt, last, pktLen, err = q.readQueryHeader(); err != nil {
+			return nil, err
+		} else if v != version || last != lastPkt {
+			return nil, NewErrMalformedPacketf("packet %v has version %v and last packet id %v, expected %v and %v", curPkt, v, last, version, lastPkt)
+		}
+	}
+
+	payload := make([]byte, 0, size)
+	for _, p := range payloads {
+		payload = append(payload, p...)
+	}
+
+	if len(payload) == 0 {
+		return &QueryResponse{Version: version, Address: q.c.Address()}, nil
+	}
+
+	return q.readQuerySinglePacket(newPacketReader(bytes.NewReader(payload)), version, requestedChunks, uint32(len(payload)))
+}
+
 func (q *queryer) readQuerySinglePacket(r *packetReader, version uint16, requestedChunks byte, pktLen uint32) (*QueryResponse, error) {
 	qr := &QueryResponse{Version: version, Address: q.c.Address()}
 
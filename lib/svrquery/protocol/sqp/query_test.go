@@ -3,9 +3,13 @@ package sqp
 import (
 	"bytes"
 	"encoding/binary"
+	"fmt"
 	"testing"
 
 	"github.com/multiplay/go-svrquery/lib/svrquery/clienttest"
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+	"github.com/multiplay/go-svrquery/lib/svrsample/common"
+	svrsqp "github.com/multiplay/go-svrquery/lib/svrsample/protocol/sqp"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
@@ -114,7 +118,7 @@ func testQueryInfoSinglePacket(t *testing.T, challengeID uint32, c *queryer) {
 
 func testQueryServerInfoSinglePacketMalformed(t *testing.T, challengeID uint32, c *queryer) {
 	_, err := c.Query()
-	require.Error(t, err, "query request should have failed")
+	require.ErrorIs(t, err, protocol.ErrMalformed, "query request should have failed")
 
 	_, ok := err.(ErrMalformedPacket)
 	require.Truef(t, ok, "expected malformed packet err, got: %v", err)
@@ -228,3 +232,88 @@ func testQueryMetricsSinglePacket(t *testing.T, challengeID uint32, c *queryer)
 	require.Equal(t, float32(438.2522), qr.Metrics.Metrics[4])
 	require.Equal(t, float32(-123.456), qr.Metrics.Metrics[5])
 }
+
+func TestPing(t *testing.T) {
+	m, c := newClient(ServerInfo)
+	req := clienttest.LoadData(t, testDir, "challenge_success_request")
+	resp := clienttest.LoadData(t, testDir, "challenge_success_response")
+	m.On("Write", req).Return(len(req), nil).Once()
+	m.On("Read", mock.AnythingOfType("[]uint8")).Return(resp, nil).Once()
+
+	require.NoError(t, c.Ping())
+	require.NotZero(t, c.challengeID)
+	m.AssertCalled(t, "Write", req)
+}
+
+func TestQueryMultiPacket(t *testing.T) {
+	state := common.QueryState{CurrentPlayers: 40, MaxPlayers: 64, Map: "Map"}
+	for i := 0; i < 40; i++ {
+		state.Players = append(state.Players, common.Record{
+			"name":  common.NewString(fmt.Sprintf("player-%d", i)),
+			"score": common.NewUint32(uint32(i)),
+		})
+	}
+	responder, err := svrsqp.NewQueryResponder(state, svrsqp.WithMaxPacketSize(200))
+	require.NoError(t, err)
+
+	addr := "127.0.0.1:8000"
+	chalReq := clienttest.LoadData(t, testDir, "challenge_success_request")
+	chalResp, err := responder.Respond(addr, chalReq)
+	require.NoError(t, err)
+
+	req := append([]byte{QueryRequestType}, chalResp[1:5]...)
+	req = append(req, 0, 1, ServerInfo|PlayerInfo)
+	pkts, err := responder.RespondPackets(addr, req)
+	require.NoError(t, err)
+	require.Greater(t, len(pkts), 2)
+
+	m, c := newClient(ServerInfo | PlayerInfo)
+	m.On("Write", chalReq).Return(len(chalReq), nil).Once()
+	m.On("Read", mock.AnythingOfType("[]uint8")).Return(chalResp, nil).Once()
+	m.On("Write", req).Return(len(req), nil).Once()
+
+	// Packets arrive in reverse order with a duplicate.
+	m.On("Read", mock.AnythingOfType("[]uint8")).Return(pkts[len(pkts)-1], nil).Once()
+	for i := len(pkts) - 1; i >= 0; i-- {
+		m.On("Read", mock.AnythingOfType("[]uint8")).Return(pkts[i], nil).Once()
+	}
+
+	r, err := c.Query()
+	require.NoError(t, err)
+
+	qr := r.(*QueryResponse)
+	require.Equal(t, uint16(40), qr.ServerInfo.CurrentPlayers)
+	require.Len(t, qr.PlayerInfo.Players, 40)
+	require.Equal(t, "player-39", qr.PlayerInfo.Players[39]["name"].String())
+	require.Equal(t, uint32(39), qr.PlayerInfo.Players[39]["score"].Uint32())
+}
+
+func TestNewCreatorChunks(t *testing.T) {
+	cases := []struct {
+		name   string
+		args   map[string]interface{}
+		chunks byte
+		err    bool
+	}{
+		{name: "default", chunks: ServerInfo},
+		{name: "byte", args: map[string]interface{}{ChunksArg: Metrics}, chunks: Metrics},
+		{name: "names", args: map[string]interface{}{ChunksArg: "info+Rules+players+teams+metrics"}, chunks: ServerInfo | ServerRules | PlayerInfo | TeamInfo | Metrics},
+		{name: "unknown", args: map[string]interface{}{ChunksArg: "info+bogus"}, err: true},
+		{name: "type", args: map[string]interface{}{ChunksArg: 1.5}, err: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := &clienttest.MockClient{}
+			m.On("Args").Return(tc.args)
+			q := newCreator(m).(*queryer)
+			if tc.err {
+				_, err := q.Query()
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, q.err)
+			require.Equal(t, tc.chunks, q.requestedChunks)
+		})
+	}
+}
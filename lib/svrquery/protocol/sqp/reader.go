@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 	"unicode/utf8"
+
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
 )
 
 // packetReader is a collection of helpers for reading
@@ -62,7 +64,7 @@ func (pr *packetReader) ReadString() (int64, string, error) {
 
 	// Check it is valid utf8
 	if !utf8.Valid(buf) {
-		return int64(n + 1), "", ErrInvalidString
+		return int64(n + 1), "", fmt.Errorf("%w: %w", protocol.ErrMalformed, ErrInvalidString)
 	}
 
 	return int64(length + 1), string(buf), err
@@ -0,0 +1,73 @@
+package teeworlds
+
+const (
+	// Version6 is the 0.6 network protocol, including the DDNet extensions.
+	Version6 = byte(6)
+
+	// Version7 is the 0.7 network protocol.
+	Version7 = byte(7)
+)
+
+// Server flags.
+const (
+	// FlagPassword indicates that the server requires a password.
+	FlagPassword = 1 << iota
+)
+
+// Client flags used by 0.7 server info responses.
+const (
+	// ClientFlagSpectator indicates that the client is spectating.
+	ClientFlagSpectator = 1 << iota
+
+	// ClientFlagBot indicates that the client is a bot.
+	ClientFlagBot
+)
+
+const (
+	// packetSize is the maximum size of a packet.
+	packetSize = 1400
+
+	// headerLength6 is the length of a 0.6 connless packet header.
+	headerLength6 = 6
+
+	// headerLength7 is the length of a 0.7 connless packet header.
+	headerLength7 = 9
+
+	// controlHeaderLength7 is the length of a 0.7 control packet header.
+	controlHeaderLength7 = 7
+
+	// tokenRequestSize7 is the padded size of a 0.7 token request payload.
+	tokenRequestSize7 = 512
+
+	// packetFlagControl7 is the 0.7 control packet flag.
+	packetFlagControl7 = 1
+
+	// packetFlagConnless7 is the 0.7 connless packet flag.
+	packetFlagConnless7 = 8
+
+	// ctrlMsgToken7 is the 0.7 token control message.
+	ctrlMsgToken7 = 5
+
+	// tokenNone7 is the 0.7 token used before a token is known.
+	tokenNone7 = 0xFFFFFFFF
+)
+
+var (
+	// connlessHeader6 is the header of a vanilla 0.6 connless packet.
+	connlessHeader6 = []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+
+	// getInfo is the server info request message.
+	getInfo = []byte{0xFF, 0xFF, 0xFF, 0xFF, 'g', 'i', 'e', '3'}
+
+	// info is the vanilla 0.6 server info response message.
+	info = []byte{0xFF, 0xFF, 0xFF, 0xFF, 'i', 'n', 'f', '3'}
+
+	// infoExtended is the DDNet extended server info response message.
+	infoExtended = []byte{0xFF, 0xFF, 0xFF, 0xFF, 'i', 'e', 'x', 't'}
+
+	// infoExtendedMore is the DDNet extended server info continuation message.
+	infoExtendedMore = []byte{0xFF, 0xFF, 0xFF, 0xFF, 'i', 'e', 'x', '+'}
+
+	// info7 is the 0.7 server info response message.
+	info7 = []byte{0xFF, 0xFF, 0xFF, 0xFF, 's', 'i', 'n', 'f'}
+)
@@ -0,0 +1,3 @@
+// Package teeworlds provides the protocol implementation for the Teeworlds
+// and DDNet connless server info requests.
+package teeworlds
@@ -0,0 +1,336 @@
+package teeworlds
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+)
+
+type queryer struct {
+	c       protocol.Client
+	version byte
+	token   func() uint32
+}
+
+func newQueryer(version byte) func(c protocol.Client) protocol.Queryer {
+	return func(c protocol.Client) protocol.Queryer {
+		return &queryer{
+			c:       c,
+			version: version,
+			token:   rand.Uint32,
+		}
+	}
+}
+
+// Query implements protocol.Queryer.
+func (q *queryer) Query() (protocol.Responser, error) {
+	if q.version >= Version7 {
+		return q.query7()
+	}
+	return q.query6()
+}
+
+// Ping implements protocol.Pinger. The 0.7 protocol uses only the token
+// exchange while the 0.6 protocol, which has no cheaper request, queries.
+func (q *queryer) Ping() error {
+	if q.version >= Version7 {
+		_, err := q.token7(q.token())
+		return err
+	}
+	_, err := q.query6()
+	return err
+}
+
+// query6 requests server info using the 0.6 protocol. The request uses the
+// DDNet extended token so DDNet servers respond with extended info, which
+// may span multiple packets, while vanilla servers respond with basic info.
+func (q *queryer) query6() (*Info, error) {
+	token := int64(q.token() & 0xFFFFFF)
+	req := []byte{'x', 'e', byte(token >> 16), byte(token >> 8), 0, 0}
+	req = append(req, getInfo...)
+	req = append(req, byte(token))
+	if _, err := q.c.Write(req); err != nil {
+		return nil, fmt.Errorf("query write: %w", err)
+	}
+
+	var i *Info
+	var clients []Client
+	seen := make(map[int64]bool)
+	b := make([]byte, packetSize)
+	for i == nil || int64(len(clients)) < i.ClientCount {
+		n, err := q.c.Read(b)
+		if err != nil {
+			return nil, fmt.Errorf("query read: %w", err)
+		}
+
+		msg, r, err := splitPacket6(b[:n])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", protocol.ErrMalformed, err)
+		}
+
+		tok, err := r.ReadIntString()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", protocol.ErrMalformed, err)
+		} else if tok != token && tok != token&0xFF {
+			// Response to an earlier request, ignore.
+			continue
+		}
+
+		switch {
+		case bytes.Equal(msg, info):
+			if i, err = readInfo6(r, false); err != nil {
+				return nil, fmt.Errorf("%w: %w", protocol.ErrMalformed, err)
+			}
+			// Vanilla responses are always a single packet.
+			if i.Clients, err = readClients6(r, false); err != nil {
+				return nil, fmt.Errorf("%w: %w", protocol.ErrMalformed, err)
+			}
+			return i, nil
+
+		case bytes.Equal(msg, infoExtended):
+			if seen[0] {
+				continue
+			}
+			seen[0] = true
+			if i, err = readInfo6(r, true); err != nil {
+				return nil, fmt.Errorf("%w: %w", protocol.ErrMalformed, err)
+			}
+
+		case bytes.Equal(msg, infoExtendedMore):
+			num, err := r.ReadIntString()
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", protocol.ErrMalformed, err)
+			} else if seen[num] {
+				continue
+			} else if _, err = r.ReadString(); err != nil { // Reserved.
+				return nil, fmt.Errorf("%w: %w", protocol.ErrMalformed, err)
+			}
+			seen[num] = true
+
+		default:
+			return nil, fmt.Errorf("%w: unexpected message %q", protocol.ErrMalformed, msg[len(msg)-4:])
+		}
+
+		c, err := readClients6(r, true)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", protocol.ErrMalformed, err)
+		}
+		clients = append(clients, c...)
+	}
+	i.Clients = clients
+
+	return i, nil
+}
+
+// splitPacket6 returns the message type of a 0.6 connless packet and a reader for its contents.
+func splitPacket6(b []byte) ([]byte, *packetReader, error) {
+	if len(b) < headerLength6+len(getInfo) {
+		return nil, nil, fmt.Errorf("packet too short (len: %d)", len(b))
+	}
+
+	if !bytes.HasPrefix(b, []byte{'x', 'e'}) && !bytes.Equal(b[:headerLength6], connlessHeader6) {
+		return nil, nil, fmt.Errorf("unexpected header %x", b[:headerLength6])
+	}
+
+	msg := b[headerLength6 : headerLength6+len(getInfo)]
+	return msg, newPacketReader(b[headerLength6+len(getInfo):]), nil
+}
+
+// readInfo6 decodes the server information of a 0.6 message, excluding the token.
+func readInfo6(r *packetReader, extended bool) (i *Info, err error) {
+	i = &Info{}
+	if i.Version, err = r.ReadString(); err != nil {
+		return nil, err
+	} else if i.Name, err = r.ReadString(); err != nil {
+		return nil, err
+	} else if i.MapName, err = r.ReadString(); err != nil {
+		return nil, err
+	}
+
+	if extended {
+		if i.MapCRC, err = r.ReadIntString(); err != nil {
+			return nil, err
+		} else if i.MapSize, err = r.ReadIntString(); err != nil {
+			return nil, err
+		}
+	}
+
+	if i.GameType, err = r.ReadString(); err != nil {
+		return nil, err
+	}
+
+	for _, v := range []*int64{&i.Flags, &i.NumPlayers, &i.MaxPlayers, &i.ClientCount, &i.MaxClientCount} {
+		if *v, err = r.ReadIntString(); err != nil {
+			return nil, err
+		}
+	}
+
+	if extended {
+		if _, err = r.ReadString(); err != nil { // Reserved.
+			return nil, err
+		}
+	}
+
+	return i, nil
+}
+
+// readClients6 decodes the clients of a 0.6 message, which continue until the end of the packet.
+func readClients6(r *packetReader, extended bool) ([]Client, error) {
+	var clients []Client
+	for {
+		name, err := r.ReadString()
+		if errors.Is(err, io.EOF) {
+			return clients, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		c := Client{Name: name}
+		var isPlayer int64
+		if c.Clan, err = r.ReadString(); err != nil {
+			return nil, err
+		} else if c.Country, err = r.ReadIntString(); err != nil {
+			return nil, err
+		} else if c.Score, err = r.ReadIntString(); err != nil {
+			return nil, err
+		} else if isPlayer, err = r.ReadIntString(); err != nil {
+			return nil, err
+		}
+		c.IsPlayer = isPlayer != 0
+
+		if extended {
+			if _, err = r.ReadString(); err != nil { // Reserved.
+				return nil, err
+			}
+		}
+		clients = append(clients, c)
+	}
+}
+
+// query7 requests server info using the 0.7 protocol, which requires
+// a token exchange before connless requests are accepted.
+func (q *queryer) query7() (*Info, error) {
+	clientToken := q.token()
+	serverToken, err := q.token7(clientToken)
+	if err != nil {
+		return nil, err
+	}
+
+	token := int64(q.token() & 0xFFFFFF)
+	req := make([]byte, headerLength7, headerLength7+len(getInfo)+4)
+	req[0] = packetFlagConnless7<<2 | 1
+	binary.BigEndian.PutUint32(req[1:], serverToken)
+	binary.BigEndian.PutUint32(req[5:], clientToken)
+	req = append(req, getInfo...)
+	req = packInt(req, token)
+	protocol.NameExchange(q.c, "info")
+	if _, err := q.c.Write(req); err != nil {
+		return nil, fmt.Errorf("query write: %w", err)
+	}
+
+	b := make([]byte, packetSize)
+	for {
+		n, err := q.c.Read(b)
+		if err != nil {
+			return nil, fmt.Errorf("query read: %w", err)
+		} else if n < headerLength7+len(info7) {
+			return nil, fmt.Errorf("%w: packet too short (len: %d)", protocol.ErrMalformed, n)
+		} else if b[0] != req[0] {
+			return nil, fmt.Errorf("%w: unexpected header %x", protocol.ErrMalformed, b[0])
+		} else if binary.BigEndian.Uint32(b[1:]) != clientToken {
+			// Not for us, ignore.
+			continue
+		}
+
+		msg := b[headerLength7 : headerLength7+len(info7)]
+		if !bytes.Equal(msg, info7) {
+			return nil, fmt.Errorf("%w: unexpected message %q", protocol.ErrMalformed, msg[len(msg)-4:])
+		}
+
+		r := newPacketReader(b[headerLength7+len(info7) : n])
+		tok, err := r.ReadInt()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", protocol.ErrMalformed, err)
+		} else if tok != token {
+			// Response to an earlier request, ignore.
+			continue
+		}
+
+		i, err := readInfo7(r)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", protocol.ErrMalformed, err)
+		}
+		return i, nil
+	}
+}
+
+// token7 performs the 0.7 token exchange and returns the server token.
+func (q *queryer) token7(clientToken uint32) (uint32, error) {
+	req := make([]byte, controlHeaderLength7+tokenRequestSize7)
+	req[0] = packetFlagControl7 << 2
+	binary.BigEndian.PutUint32(req[3:], tokenNone7)
+	req[controlHeaderLength7] = ctrlMsgToken7
+	binary.BigEndian.PutUint32(req[controlHeaderLength7+1:], clientToken)
+	protocol.NameExchange(q.c, "token")
+	if _, err := q.c.Write(req); err != nil {
+		return 0, fmt.Errorf("token write: %w", err)
+	}
+
+	b := make([]byte, packetSize)
+	n, err := q.c.Read(b)
+	if err != nil {
+		return 0, fmt.Errorf("token read: %w", err)
+	} else if n < controlHeaderLength7+5 {
+		return 0, fmt.Errorf("%w: packet too short (len: %d)", protocol.ErrMalformed, n)
+	} else if b[0]>>2&0xF != packetFlagControl7 || b[controlHeaderLength7] != ctrlMsgToken7 {
+		return 0, fmt.Errorf("%w: unexpected token response %x", protocol.ErrMalformed, b[:controlHeaderLength7+1])
+	} else if tok := binary.BigEndian.Uint32(b[3:]); tok != clientToken {
+		return 0, fmt.Errorf("%w: unexpected token %x (expected %x)", protocol.ErrChallenge, tok, clientToken)
+	}
+
+	return binary.BigEndian.Uint32(b[controlHeaderLength7+1:]), nil
+}
+
+// readInfo7 decodes a 0.7 message, excluding the token.
+func readInfo7(r *packetReader) (i *Info, err error) {
+	i = &Info{}
+	for _, s := range []*string{&i.Version, &i.Name, &i.Hostname, &i.MapName, &i.GameType} {
+		if *s, err = r.ReadString(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, v := range []*int64{&i.Flags, &i.SkillLevel, &i.NumPlayers, &i.MaxPlayers, &i.ClientCount, &i.MaxClientCount} {
+		if *v, err = r.ReadInt(); err != nil {
+			return nil, err
+		}
+	}
+
+	for {
+		name, err := r.ReadString()
+		if errors.Is(err, io.EOF) {
+			return i, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		c := Client{Name: name}
+		var flags int64
+		if c.Clan, err = r.ReadString(); err != nil {
+			return nil, err
+		} else if c.Country, err = r.ReadInt(); err != nil {
+			return nil, err
+		} else if c.Score, err = r.ReadInt(); err != nil {
+			return nil, err
+		} else if flags, err = r.ReadInt(); err != nil {
+			return nil, err
+		}
+		c.IsPlayer = flags&ClientFlagSpectator == 0
+		i.Clients = append(i.Clients, c)
+	}
+}
@@ -0,0 +1,145 @@
+package teeworlds
+
+import (
+	"testing"
+
+	"github.com/multiplay/go-svrquery/lib/svrquery/clienttest"
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testDir = "testdata"
+)
+
+func TestQuery(t *testing.T) {
+	cases := []struct {
+		name      string
+		version   byte
+		tokens    []uint32
+		requests  []string
+		responses []string
+		expected  *Info
+	}{
+		{
+			name:      "vanilla",
+			version:   Version6,
+			tokens:    []uint32{0xFF123456},
+			requests:  []string{"info_request"},
+			responses: []string{"info_response"},
+			expected: &Info{
+				Version:        "0.6.4",
+				Name:           "Vanilla",
+				MapName:        "dm1",
+				GameType:       "DM",
+				Flags:          1,
+				NumPlayers:     1,
+				MaxPlayers:     16,
+				ClientCount:    2,
+				MaxClientCount: 16,
+				Clients: []Client{
+					{Name: "alice", Clan: "clan", Country: 276, Score: 10, IsPlayer: true},
+					{Name: "bob", Country: -1},
+				},
+			},
+		},
+		{
+			name:      "ddnet",
+			version:   Version6,
+			tokens:    []uint32{0x123456},
+			requests:  []string{"info_request"},
+			responses: []string{"iexmore_response", "stale_response", "iext_response"},
+			expected: &Info{
+				Version:        "0.6.4, 17.0",
+				Name:           "DDNet Server",
+				MapName:        "Multeasymap",
+				MapCRC:         1234567,
+				MapSize:        98765,
+				GameType:       "DDraceNetwork",
+				NumPlayers:     2,
+				MaxPlayers:     64,
+				ClientCount:    3,
+				MaxClientCount: 64,
+				Clients: []Client{
+					{Name: "carol", Clan: "x", Score: -9999},
+					{Name: "alice", Clan: "clan", Country: 276, Score: -9999, IsPlayer: true},
+					{Name: "bob", Country: -1, Score: -9999, IsPlayer: true},
+				},
+			},
+		},
+		{
+			name:      "v7",
+			version:   Version7,
+			tokens:    []uint32{0xAABBCCDD, 0x12345678},
+			requests:  []string{"token7_request", "info7_request"},
+			responses: []string{"token7_response", "info7_response"},
+			expected: &Info{
+				Version:        "0.7.5",
+				Name:           "Teeworlds 0.7",
+				Hostname:       "tw.example.com",
+				MapName:        "ctf5",
+				GameType:       "CTF",
+				Flags:          1,
+				SkillLevel:     2,
+				NumPlayers:     1,
+				MaxPlayers:     16,
+				ClientCount:    2,
+				MaxClientCount: 16,
+				Clients: []Client{
+					{Name: "alice", Clan: "clan", Country: 276, Score: 1000, IsPlayer: true},
+					{Name: "bob", Country: -1, Score: -5},
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mc := &clienttest.MockClient{}
+			for _, name := range tc.requests {
+				req := clienttest.LoadData(t, testDir, name)
+				mc.On("Write", req).Return(len(req), nil).Once()
+			}
+			for _, name := range tc.responses {
+				resp := clienttest.LoadData(t, testDir, name)
+				mc.On("Read", mock.AnythingOfType("[]uint8")).Return(resp, nil).Once()
+			}
+
+			tokens := tc.tokens
+			q := &queryer{
+				c:       mc,
+				version: tc.version,
+				token: func() uint32 {
+					tok := tokens[0]
+					tokens = tokens[1:]
+					return tok
+				},
+			}
+
+			i, err := q.Query()
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, i)
+			mc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestInt(t *testing.T) {
+	for _, v := range []int64{0, 1, 63, 64, -1, -64, -65, 1000, 1 << 20, -(1 << 20)} {
+		r := newPacketReader(packInt(nil, v))
+		got, err := r.ReadInt()
+		require.NoError(t, err)
+		require.Equal(t, v, got)
+	}
+}
+
+func TestPlayers(t *testing.T) {
+	i := &Info{
+		Clients: []Client{
+			{Name: "alice", Clan: "red", Score: 20, IsPlayer: true},
+			{Name: "bob", Score: 0},
+		},
+	}
+	require.Equal(t, []protocol.Player{{Name: "alice", Score: 20}, {Name: "bob"}}, i.Players())
+}
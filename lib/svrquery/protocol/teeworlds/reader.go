@@ -0,0 +1,75 @@
+package teeworlds
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+
+	"github.com/multiplay/go-svrquery/lib/svrquery/common"
+)
+
+// packetReader is a collection of helpers for reading the
+// parts of a server info message.
+type packetReader struct {
+	*common.BinaryReader
+}
+
+// newPacketReader returns a new packetReader which reads from b.
+func newPacketReader(b []byte) *packetReader {
+	return &packetReader{common.NewBinaryReader(b, binary.BigEndian)}
+}
+
+// ReadIntString reads a null terminated decimal string as used by 0.6 messages.
+func (r *packetReader) ReadIntString() (int64, error) {
+	s, err := r.ReadString()
+	if err != nil {
+		return 0, err
+	}
+
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer %q: %w", s, err)
+	}
+	return v, nil
+}
+
+// ReadInt reads a variable length integer as used by 0.7 messages.
+// The first byte holds an extend bit, a sign bit and six bits of data
+// while following bytes hold an extend bit and seven bits of data.
+func (r *packetReader) ReadInt() (int64, error) {
+	var b byte
+	if err := r.Read(&b); err != nil {
+		return 0, err
+	}
+
+	sign := int64(b>>6) & 1
+	v := int64(b & 0x3F)
+	for shift := 6; b&0x80 != 0; shift += 7 {
+		if shift > 27 {
+			return 0, fmt.Errorf("integer too long")
+		} else if err := r.Read(&b); err != nil {
+			return 0, err
+		}
+		v |= int64(b&0x7F) << shift
+	}
+
+	return v ^ -sign, nil
+}
+
+// packInt appends v to b as a variable length integer.
+func packInt(b []byte, v int64) []byte {
+	c := byte(0)
+	if v < 0 {
+		c = 0x40
+		v = ^v
+	}
+
+	c |= byte(v & 0x3F)
+	v >>= 6
+	for v != 0 {
+		b = append(b, c|0x80)
+		c = byte(v & 0x7F)
+		v >>= 7
+	}
+	return append(b, c)
+}
@@ -0,0 +1,10 @@
+package teeworlds
+
+import (
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+)
+
+func init() {
+	protocol.MustRegister("teeworlds", newQueryer(Version6))
+	protocol.MustRegister("teeworlds-v7", newQueryer(Version7))
+}
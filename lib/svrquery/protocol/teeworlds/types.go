@@ -0,0 +1,64 @@
+package teeworlds
+
+import (
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+)
+
+// Info represents a full query response.
+type Info struct {
+	Version        string   `json:"version"`
+	Name           string   `json:"name"`
+	Hostname       string   `json:"hostname,omitempty"`
+	MapName        string   `json:"map"`
+	MapCRC         int64    `json:"map_crc,omitempty"`
+	MapSize        int64    `json:"map_size,omitempty"`
+	GameType       string   `json:"game_type"`
+	Flags          int64    `json:"flags"`
+	SkillLevel     int64    `json:"skill_level,omitempty"`
+	NumPlayers     int64    `json:"num_players"`
+	MaxPlayers     int64    `json:"max_players"`
+	ClientCount    int64    `json:"num_clients"`
+	MaxClientCount int64    `json:"max_clients"`
+	Clients        []Client `json:"clients,omitempty"`
+}
+
+// NumClients implements protocol.Responser.
+func (i *Info) NumClients() int64 {
+	return i.ClientCount
+}
+
+// MaxClients implements protocol.Responser.
+func (i *Info) MaxClients() int64 {
+	return i.MaxClientCount
+}
+
+// Map implements protocol.Mapper.
+func (i *Info) Map() string {
+	return i.MapName
+}
+
+// Players implements protocol.PlayerLister, including spectators.
+func (i *Info) Players() []protocol.Player {
+	players := make([]protocol.Player, len(i.Clients))
+	for j, p := range i.Clients {
+		players[j] = protocol.Player{
+			Name:  p.Name,
+			Score: p.Score,
+		}
+	}
+	return players
+}
+
+// Passworded returns true if the server requires a password.
+func (i *Info) Passworded() bool {
+	return i.Flags&FlagPassword != 0
+}
+
+// Client represents a client in a query response.
+type Client struct {
+	Name     string `json:"name"`
+	Clan     string `json:"clan"`
+	Country  int64  `json:"country"`
+	Score    int64  `json:"score"`
+	IsPlayer bool   `json:"is_player"`
+}
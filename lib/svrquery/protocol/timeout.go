@@ -0,0 +1,12 @@
+package protocol
+
+import "time"
+
+// ReadTimeout reads from c waiting up to timeout if it implements
+// TimeoutReader, otherwise it reads with the client's own timeout.
+func ReadTimeout(c Client, b []byte, timeout time.Duration) (int, error) {
+	if r, ok := c.(TimeoutReader); ok {
+		return r.ReadTimeout(b, timeout)
+	}
+	return c.Read(b)
+}
@@ -73,12 +73,12 @@ func (q *queryer) encrypt(b []byte) ([]byte, error) {
 func (q *queryer) decrypt(b []byte) ([]byte, error) {
 	keyBytes, err := base64.StdEncoding.DecodeString(q.c.Key())
 	if err != nil {
-		return nil, fmt.Errorf("decode key: %w", err)
+		return nil, fmt.Errorf("%w: decode key: %w", protocol.ErrAuth, err)
 	}
 
 	c, err := aes.NewCipher(keyBytes)
 	if err != nil {
-		return nil, fmt.Errorf("new aes cipher: %w", err)
+		return nil, fmt.Errorf("%w: new aes cipher: %w", protocol.ErrAuth, err)
 	}
 
 	gcm, err := cipher.NewGCM(c)
@@ -86,15 +86,15 @@ func (q *queryer) decrypt(b []byte) ([]byte, error) {
 		return nil, fmt.Errorf("new gcm: %w", err)
 	}
 
-	if len(b) < gcm.NonceSize() {
-		return nil, fmt.Errorf("incoming bytes smaller than %d", gcm.NonceSize())
+	if len(b) < gcm.NonceSize()+tagSize {
+		return nil, fmt.Errorf("%w: incoming bytes smaller than %d", protocol.ErrMalformed, gcm.NonceSize()+tagSize)
 	}
 
 	nonce, tag, b := b[:gcm.NonceSize()], b[gcm.NonceSize():gcm.NonceSize()+tagSize], b[gcm.NonceSize()+tagSize:]
 	b = append(b, tag...)
 	plaintext, err := gcm.Open(nil, nonce, b, gcmAdditionalData)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %w", protocol.ErrAuth, err)
 	}
 
 	return plaintext, nil
@@ -129,7 +129,7 @@ func (q *queryer) Query() (resp protocol.Responser, err error) {
 	if err != nil {
 		return nil, fmt.Errorf("query read: %w", err)
 	} else if n < minLength {
-		return nil, fmt.Errorf("packet too short (len: %d)", n)
+		return nil, fmt.Errorf("%w: packet too short (len: %d)", protocol.ErrMalformed, n)
 	}
 
 	if q.version >= 8 && q.c.Key() != "" {
@@ -139,8 +139,17 @@ func (q *queryer) Query() (resp protocol.Responser, err error) {
 		}
 	}
 
+	i, err := q.info(b)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", protocol.ErrMalformed, err)
+	}
+	return i, nil
+}
+
+// info decodes a server info response.
+func (q *queryer) info(b []byte) (i *Info, err error) {
 	r := common.NewBinaryReader(b, binary.LittleEndian)
-	i := &Info{}
+	i = &Info{}
 
 	// Header.
 	if err = r.Read(&i.Header); err != nil {
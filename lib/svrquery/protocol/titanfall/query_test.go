@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/multiplay/go-svrquery/lib/svrquery/clienttest"
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
@@ -248,3 +249,22 @@ Line 8: Some test text to be encrypted and decrypted`
 	require.NoError(t, err)
 	require.Equal(t, text, string(decoded))
 }
+
+func TestDecryptWrongKey(t *testing.T) {
+	mc := &clienttest.MockClient{}
+	mc.On("Key").Return("Z2ZkZ3Nnbmpza2U0cnRyZQ==").Once()
+	p := queryer{
+		c: mc,
+	}
+
+	encoded, err := p.encrypt([]byte("some text"))
+	require.NoError(t, err)
+
+	mc.On("Key").Return("AAAAAAAAAAAAAAAAAAAAAA==").Once()
+	_, err = p.decrypt(encoded)
+	require.ErrorIs(t, err, protocol.ErrAuth)
+
+	mc.On("Key").Return("Z2ZkZ3Nnbmpza2U0cnRyZQ==").Once()
+	_, err = p.decrypt(encoded[:10])
+	require.ErrorIs(t, err, protocol.ErrMalformed)
+}
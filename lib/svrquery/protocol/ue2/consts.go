@@ -0,0 +1,33 @@
+package ue2
+
+import "time"
+
+// Request and response types.
+const (
+	// ServerInfo is the type of a server information packet.
+	ServerInfo = byte(0x00)
+
+	// Rules is the type of a rules packet.
+	Rules = byte(0x01)
+
+	// Players is the type of a players packet.
+	Players = byte(0x02)
+)
+
+const (
+	// requestPrefix is the prefix of a request packet.
+	requestPrefix = byte(0x79)
+
+	// headerLength is the length of the header of a packet including its type.
+	headerLength = 5
+
+	// colorCode is the escape which starts a 4 byte colour code.
+	colorCode = byte(0x1B)
+
+	// packetSize is the maximum size of a response packet.
+	packetSize = 1400
+
+	// quietPeriod is how long to wait for more packets once a packet of each
+	// type has arrived.
+	quietPeriod = time.Millisecond * 100
+)
@@ -0,0 +1,3 @@
+// Package ue2 provides the protocol implementation for the Unreal Engine 2
+// query protocol used by Unreal Tournament 2004 and Killing Floor.
+package ue2
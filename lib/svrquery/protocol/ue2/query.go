@@ -0,0 +1,228 @@
+package ue2
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf16"
+
+	"github.com/multiplay/go-svrquery/lib/svrquery/common"
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+)
+
+type queryer struct {
+	c protocol.Client
+}
+
+func newQueryer(c protocol.Client) protocol.Queryer {
+	return &queryer{c: c}
+}
+
+// Query implements protocol.Queryer.
+//
+// Rules and players responses can span multiple packets with no indication
+// of how many, and the responses to each request can arrive in any order, so
+// packets are read until the server info and at least one rules and players
+// packet have arrived, then until no packet arrives for quietPeriod so later
+// packets aren't left to be read by the next query. Servers which don't
+// respond to the rules or players requests are read until the timeout,
+// returning what was received.
+func (q *queryer) Query() (protocol.Responser, error) {
+	for _, t := range []byte{Rules, Players, ServerInfo} {
+		if _, err := q.c.Write([]byte{requestPrefix, 0, 0, 0, t}); err != nil {
+			return nil, fmt.Errorf("query write: %w", err)
+		}
+	}
+
+	var i *Info
+	var rules []*common.BinaryReader
+	var players []*common.BinaryReader
+	b := make([]byte, packetSize)
+	for {
+		var n int
+		var err error
+		if i != nil && len(rules) > 0 && len(players) > 0 {
+			n, err = protocol.ReadTimeout(q.c, b, quietPeriod)
+		} else {
+			n, err = q.c.Read(b)
+		}
+
+		if err != nil {
+			if i != nil && errors.Is(err, protocol.ErrTimeout) {
+				break
+			}
+			return nil, fmt.Errorf("query read: %w", err)
+		} else if n < headerLength {
+			return nil, fmt.Errorf("%w: packet too short (len: %d)", protocol.ErrMalformed, n)
+		}
+
+		// Each packet needs its own copy as the buffer is reused.
+		r := common.NewBinaryReader(append([]byte(nil), b[headerLength:n]...), binary.LittleEndian)
+		switch t := b[headerLength-1]; t {
+		case Rules:
+			rules = append(rules, r)
+		case Players:
+			players = append(players, r)
+		case ServerInfo:
+			if i, err = readServerInfo(r); err != nil {
+				return nil, fmt.Errorf("%w: server info: %w", protocol.ErrMalformed, err)
+			}
+		default:
+			return nil, fmt.Errorf("%w: unexpected packet type %x", protocol.ErrMalformed, t)
+		}
+	}
+
+	var err error
+	if i.ServerRules, err = readRules(rules); err != nil {
+		return nil, fmt.Errorf("%w: rules: %w", protocol.ErrMalformed, err)
+	} else if i.PlayerList, err = readPlayers(players); err != nil {
+		return nil, fmt.Errorf("%w: players: %w", protocol.ErrMalformed, err)
+	}
+	return i, nil
+}
+
+// Ping implements protocol.Pinger using only the server info request.
+func (q *queryer) Ping() error {
+	if _, err := q.c.Write([]byte{requestPrefix, 0, 0, 0, ServerInfo}); err != nil {
+		return fmt.Errorf("ping write: %w", err)
+	}
+
+	b := make([]byte, packetSize)
+	if _, err := q.c.Read(b); err != nil {
+		return fmt.Errorf("ping read: %w", err)
+	}
+	return nil
+}
+
+// readServerInfo decodes a server info packet.
+func readServerInfo(r *common.BinaryReader) (i *Info, err error) {
+	i = &Info{}
+	if err = r.Read(&i.ServerID); err != nil {
+		return nil, err
+	} else if i.ServerIP, err = readString(r); err != nil {
+		return nil, err
+	} else if err = r.Read(&i.GamePort); err != nil {
+		return nil, err
+	} else if err = r.Read(&i.QueryPort); err != nil {
+		return nil, err
+	} else if i.ServerName, err = readString(r); err != nil {
+		return nil, err
+	} else if i.MapName, err = readString(r); err != nil {
+		return nil, err
+	} else if i.GameType, err = readString(r); err != nil {
+		return nil, err
+	} else if err = r.Read(&i.NumPlayers); err != nil {
+		return nil, err
+	} else if err = r.Read(&i.MaxPlayers); err != nil {
+		return nil, err
+	}
+
+	// Older servers omit the trailing fields.
+	if err = r.Read(&i.Ping); errors.Is(err, io.EOF) {
+		return i, nil
+	} else if err != nil {
+		return nil, err
+	} else if err = r.Read(&i.Flags); err != nil {
+		return nil, err
+	} else if i.Skill, err = readString(r); err != nil {
+		return nil, err
+	}
+
+	return i, nil
+}
+
+// readRules decodes the key value pairs of the rules packets.
+func readRules(packets []*common.BinaryReader) (map[string]string, error) {
+	rules := make(map[string]string)
+	for _, r := range packets {
+		for {
+			k, err := readString(r)
+			if errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+
+			if rules[k], err = readString(r); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return rules, nil
+}
+
+// readPlayers decodes the players of the players packets.
+func readPlayers(packets []*common.BinaryReader) ([]Player, error) {
+	var players []Player
+	for _, r := range packets {
+		for {
+			var p Player
+			if err := r.Read(&p.ID); errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+
+			var err error
+			if p.Name, err = readString(r); err != nil {
+				return nil, err
+			} else if err = r.Read(&p.Ping); err != nil {
+				return nil, err
+			} else if err = r.Read(&p.Score); err != nil {
+				return nil, err
+			} else if err = r.Read(&p.StatsID); err != nil {
+				return nil, err
+			}
+			players = append(players, p)
+		}
+	}
+	return players, nil
+}
+
+// readString reads a length prefixed string. If the top bit of the length is
+// set the string is UCS-2 encoded with the remaining bits being the number of
+// characters, otherwise it is Latin-1. Strings are null terminated and colour
+// codes are removed.
+func readString(r *common.BinaryReader) (string, error) {
+	var l byte
+	if err := r.Read(&l); err != nil {
+		return "", err
+	}
+
+	var s []rune
+	if l&0x80 != 0 {
+		u := make([]uint16, l&0x7F)
+		if err := r.Read(u); err != nil {
+			return "", err
+		}
+		s = utf16.Decode(u)
+	} else {
+		b := make([]byte, l)
+		if err := r.Read(b); err != nil {
+			return "", err
+		}
+		s = make([]rune, len(b))
+		for i, c := range b {
+			s[i] = rune(c)
+		}
+	}
+
+	return stripColors(s), nil
+}
+
+// stripColors returns s as a string without its null terminator or colour codes.
+func stripColors(s []rune) string {
+	out := make([]rune, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == 0:
+			return string(out)
+		case s[i] == rune(colorCode):
+			i += 3
+		default:
+			out = append(out, s[i])
+		}
+	}
+	return string(out)
+}
@@ -0,0 +1,183 @@
+package ue2
+
+import (
+	"testing"
+
+	"github.com/multiplay/go-svrquery/lib/svrquery/clienttest"
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testDir = "testdata"
+)
+
+func TestQuery(t *testing.T) {
+	cases := []struct {
+		name      string
+		responses []string
+		expected  *Info
+		err       error
+	}{
+		{
+			name:      "full",
+			responses: []string{"rules_1", "players", "rules_2", "info"},
+			expected: &Info{
+				ServerID:   1,
+				ServerIP:   "1.2.3.4",
+				GamePort:   7777,
+				QueryPort:  7778,
+				ServerName: "Red Server",
+				MapName:    "DM-Rankin",
+				GameType:   "xDeathMatch",
+				NumPlayers: 2,
+				MaxPlayers: 16,
+				ServerRules: map[string]string{
+					"AdminName":     "admin",
+					"MaxSpectators": "2",
+					"Mutator":       "MutInstaGib",
+				},
+				PlayerList: []Player{
+					{ID: 1, Name: "élise", Ping: 50, Score: 20},
+					{ID: 2, Name: "bob", Ping: 80, Score: -1},
+				},
+			},
+		},
+		{
+			name:      "out_of_order",
+			responses: []string{"info", "rules_1", "rules_2", "players"},
+			expected: &Info{
+				ServerID:   1,
+				ServerIP:   "1.2.3.4",
+				GamePort:   7777,
+				QueryPort:  7778,
+				ServerName: "Red Server",
+				MapName:    "DM-Rankin",
+				GameType:   "xDeathMatch",
+				NumPlayers: 2,
+				MaxPlayers: 16,
+				ServerRules: map[string]string{
+					"AdminName":     "admin",
+					"MaxSpectators": "2",
+					"Mutator":       "MutInstaGib",
+				},
+				PlayerList: []Player{
+					{ID: 1, Name: "élise", Ping: 50, Score: 20},
+					{ID: 2, Name: "bob", Ping: 80, Score: -1},
+				},
+			},
+		},
+		{
+			name:      "late_packets",
+			responses: []string{"info", "rules_1", "players", "rules_2", "players_2"},
+			expected: &Info{
+				ServerID:   1,
+				ServerIP:   "1.2.3.4",
+				GamePort:   7777,
+				QueryPort:  7778,
+				ServerName: "Red Server",
+				MapName:    "DM-Rankin",
+				GameType:   "xDeathMatch",
+				NumPlayers: 2,
+				MaxPlayers: 16,
+				ServerRules: map[string]string{
+					"AdminName":     "admin",
+					"MaxSpectators": "2",
+					"Mutator":       "MutInstaGib",
+				},
+				PlayerList: []Player{
+					{ID: 1, Name: "élise", Ping: 50, Score: 20},
+					{ID: 2, Name: "bob", Ping: 80, Score: -1},
+					{ID: 3, Name: "carol", Ping: 30, Score: 5},
+				},
+			},
+		},
+		{
+			name:      "short_info",
+			responses: []string{"info_short"},
+			expected: &Info{
+				ServerID:    1,
+				ServerIP:    "1.2.3.4",
+				GamePort:    7777,
+				QueryPort:   7778,
+				ServerName:  "Old",
+				MapName:     "DM-Deck17",
+				GameType:    "xDeathMatch",
+				MaxPlayers:  8,
+				ServerRules: map[string]string{},
+			},
+		},
+		{
+			name:      "no_info",
+			responses: []string{"rules_1", "players"},
+			err:       protocol.ErrTimeout,
+		},
+	}
+
+	requests := clienttest.LoadData(t, testDir, "requests")
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mc := &clienttest.MockClient{}
+			for i := 0; i < len(requests); i += headerLength {
+				req := requests[i : i+headerLength]
+				mc.On("Write", req).Return(len(req), nil).Once()
+			}
+			for _, name := range tc.responses {
+				resp := clienttest.LoadData(t, testDir, name+"_response")
+				mc.On("Read", mock.AnythingOfType("[]uint8")).Return(resp, nil).Once()
+			}
+			mc.On("Read", mock.AnythingOfType("[]uint8")).Return([]byte{}, protocol.ErrTimeout).Once()
+
+			i, err := newQueryer(mc).Query()
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, i)
+			mc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestQueryLatePacketsNotLeft(t *testing.T) {
+	mc := &clienttest.MockClient{}
+	requests := clienttest.LoadData(t, testDir, "requests")
+	for i := 0; i < len(requests); i += headerLength {
+		req := requests[i : i+headerLength]
+		mc.On("Write", req).Return(len(req), nil).Twice()
+	}
+	for _, name := range []string{"info", "rules_1", "players", "rules_2", "players_2", "", "info", "rules_1", "players", ""} {
+		if name == "" {
+			mc.On("Read", mock.AnythingOfType("[]uint8")).Return([]byte{}, protocol.ErrTimeout).Once()
+			continue
+		}
+		resp := clienttest.LoadData(t, testDir, name+"_response")
+		mc.On("Read", mock.AnythingOfType("[]uint8")).Return(resp, nil).Once()
+	}
+
+	q := newQueryer(mc)
+	r, err := q.Query()
+	require.NoError(t, err)
+	require.Len(t, r.(*Info).ServerRules, 3)
+	require.Len(t, r.(*Info).PlayerList, 3)
+
+	r, err = q.Query()
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"AdminName": "admin", "MaxSpectators": "2"}, r.(*Info).ServerRules)
+	require.Equal(t, []Player{
+		{ID: 1, Name: "élise", Ping: 50, Score: 20},
+		{ID: 2, Name: "bob", Ping: 80, Score: -1},
+	}, r.(*Info).PlayerList)
+	mc.AssertExpectations(t)
+}
+
+func TestPlayers(t *testing.T) {
+	i := &Info{
+		PlayerList: []Player{
+			{ID: 1, Name: "alice", Ping: 50, Score: 20, StatsID: 3},
+		},
+	}
+	require.Equal(t, []protocol.Player{{Name: "alice", Score: 20, Ping: 50}}, i.Players())
+}
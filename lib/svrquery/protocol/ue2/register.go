@@ -0,0 +1,9 @@
+package ue2
+
+import (
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+)
+
+func init() {
+	protocol.MustRegister("ue2", newQueryer)
+}
@@ -0,0 +1,65 @@
+package ue2
+
+import (
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+)
+
+// Info represents a full query response.
+type Info struct {
+	ServerID    uint32            `json:"server_id"`
+	ServerIP    string            `json:"server_ip"`
+	GamePort    uint32            `json:"game_port"`
+	QueryPort   uint32            `json:"query_port"`
+	ServerName  string            `json:"server_name"`
+	MapName     string            `json:"map"`
+	GameType    string            `json:"game_type"`
+	NumPlayers  uint32            `json:"num_players"`
+	MaxPlayers  uint32            `json:"max_players"`
+	Ping        uint32            `json:"ping"`
+	Flags       int32             `json:"flags"`
+	Skill       string            `json:"skill"`
+	ServerRules map[string]string `json:"rules,omitempty"`
+	PlayerList  []Player          `json:"players,omitempty"`
+}
+
+// NumClients implements protocol.Responser.
+func (i *Info) NumClients() int64 {
+	return int64(i.NumPlayers)
+}
+
+// MaxClients implements protocol.Responser.
+func (i *Info) MaxClients() int64 {
+	return int64(i.MaxPlayers)
+}
+
+// Map implements protocol.Mapper.
+func (i *Info) Map() string {
+	return i.MapName
+}
+
+// Rules implements protocol.Ruler.
+func (i *Info) Rules() map[string]string {
+	return i.ServerRules
+}
+
+// Players implements protocol.PlayerLister.
+func (i *Info) Players() []protocol.Player {
+	players := make([]protocol.Player, len(i.PlayerList))
+	for j, p := range i.PlayerList {
+		players[j] = protocol.Player{
+			Name:  p.Name,
+			Score: int64(p.Score),
+			Ping:  int64(p.Ping),
+		}
+	}
+	return players
+}
+
+// Player represents a player in a query response.
+type Player struct {
+	ID      uint32 `json:"id"`
+	Name    string `json:"name"`
+	Ping    uint32 `json:"ping"`
+	Score   int32  `json:"score"`
+	StatsID uint32 `json:"stats_id"`
+}
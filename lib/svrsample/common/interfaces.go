@@ -6,13 +6,89 @@ type QueryResponder interface {
 	Respond(clientAddress string, buf []byte) ([]byte, error)
 }
 
+// MultiPacketResponder represents a QueryResponder which can respond
+// to a query with multiple packets.
+type MultiPacketResponder interface {
+	RespondPackets(clientAddress string, buf []byte) ([][]byte, error)
+}
+
+// Matcher represents a detector of the requests of a protocol.
+type Matcher interface {
+	// Match returns true if buf is a request of the protocol.
+	Match(buf []byte) bool
+}
+
+// MatcherFunc is an adapter to allow the use of ordinary functions as a Matcher.
+type MatcherFunc func(buf []byte) bool
+
+// Match implements Matcher.
+func (f MatcherFunc) Match(buf []byte) bool {
+	return f(buf)
+}
+
+// AddressVerifier represents a QueryResponder which only sends responses
+// larger than the request to clients which have proven their address, such
+// as with a challenge, so its responses don't need to be limited to prevent
+// amplification attacks.
+type AddressVerifier interface {
+	// VerifiesAddress returns true if the response to req is only larger
+	// than req if the client has proven its address.
+	VerifiesAddress(req []byte) bool
+}
+
+// Corrupter represents a QueryResponder which can corrupt its response
+// packets in protocol specific ways, used to inject faults. The packets
+// pkts, which are the response to req, are corrupted in place.
+type Corrupter interface {
+	// CorruptChallenge changes the challenge of pkts, returning false if they have none.
+	CorruptChallenge(req []byte, pkts [][]byte) bool
+
+	// CorruptDataType changes a data type of pkts to an invalid value,
+	// returning false if they have none.
+	CorruptDataType(req []byte, pkts [][]byte) bool
+}
+
 // QueryState represents the state of a currently running game.
 type QueryState struct {
-	CurrentPlayers int32
-	MaxPlayers     int32
-	ServerName     string
-	GameType       string
-	Map            string
-	Port           uint16
-	Metrics        []float32
+	CurrentPlayers int32                   `json:"current_players"`
+	MaxPlayers     int32                   `json:"max_players"`
+	ServerName     string                  `json:"server_name"`
+	GameType       string                  `json:"game_type"`
+	BuildID        string                  `json:"build_id"`
+	Map            string                  `json:"map"`
+	Port           uint16                  `json:"port"`
+	Rules          map[string]DynamicValue `json:"rules,omitempty"`
+	Players        []Record                `json:"players,omitempty"`
+	Teams          []Record                `json:"teams,omitempty"`
+	Metrics        []float32               `json:"metrics,omitempty"`
+}
+
+// Clone returns a copy of the state which shares no memory with it.
+func (qs QueryState) Clone() QueryState {
+	if qs.Rules != nil {
+		rules := make(map[string]DynamicValue, len(qs.Rules))
+		for k, v := range qs.Rules {
+			rules[k] = v
+		}
+		qs.Rules = rules
+	}
+	qs.Players = cloneRecords(qs.Players)
+	qs.Teams = cloneRecords(qs.Teams)
+	if qs.Metrics != nil {
+		qs.Metrics = append([]float32(nil), qs.Metrics...)
+	}
+	return qs
+}
+
+// cloneRecords returns a copy of records which shares no memory with it.
+func cloneRecords(records []Record) []Record {
+	if records == nil {
+		return nil
+	}
+
+	c := make([]Record, len(records))
+	for i, r := range records {
+		c[i] = r.clone()
+	}
+	return c
 }
@@ -0,0 +1,71 @@
+package common
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// IPFilter allows or denies addresses using lists of CIDRs.
+type IPFilter struct {
+	allow []*net.IPNet
+	deny  []*net.IPNet
+}
+
+// NewIPFilter returns an IPFilter which denies addresses in deny and, if allow
+// isn't empty, those not in allow. Entries are CIDRs or single IP addresses.
+func NewIPFilter(allow, deny []string) (*IPFilter, error) {
+	var f IPFilter
+	var err error
+	if f.allow, err = parseCIDRs(allow); err != nil {
+		return nil, fmt.Errorf("allow: %w", err)
+	} else if f.deny, err = parseCIDRs(deny); err != nil {
+		return nil, fmt.Errorf("deny: %w", err)
+	}
+	return &f, nil
+}
+
+// parseCIDRs parses a list of CIDRs or single IP addresses.
+func parseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, c := range cidrs {
+		if !strings.Contains(c, "/") {
+			ip := net.ParseIP(c)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid ip %q", c)
+			}
+
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// Allowed returns true if ip is allowed.
+func (f *IPFilter) Allowed(ip net.IP) bool {
+	if contains(f.deny, ip) {
+		return false
+	}
+	return len(f.allow) == 0 || contains(f.allow, ip)
+}
+
+// contains returns true if any of nets contain ip.
+func contains(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
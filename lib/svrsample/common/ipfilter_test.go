@@ -0,0 +1,37 @@
+package common
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPFilter(t *testing.T) {
+	f, err := NewIPFilter([]string{"10.0.0.0/8", "2001:db8::/32", "192.168.1.1"}, []string{"10.1.0.0/16"})
+	require.NoError(t, err)
+
+	for ip, allowed := range map[string]bool{
+		"10.0.0.1":         true,
+		"::ffff:10.0.0.1":  true,
+		"10.1.0.1":         false,
+		"192.168.1.1":      true,
+		"192.168.1.2":      false,
+		"2001:db8::1":      true,
+		"2001:db9::1":      false,
+		"127.0.0.1":        false,
+		"::ffff:127.0.0.1": false,
+	} {
+		require.Equal(t, allowed, f.Allowed(net.ParseIP(ip)), ip)
+	}
+
+	f, err = NewIPFilter(nil, []string{"::1"})
+	require.NoError(t, err)
+	require.False(t, f.Allowed(net.ParseIP("::1")))
+	require.True(t, f.Allowed(net.ParseIP("127.0.0.1")))
+
+	_, err = NewIPFilter([]string{"10.0.0.0/33"}, nil)
+	require.Error(t, err)
+	_, err = NewIPFilter(nil, []string{"host"})
+	require.Error(t, err)
+}
@@ -0,0 +1,85 @@
+package common
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// DefaultMaxSources is the default maximum number of sources a RateLimiter tracks.
+const DefaultMaxSources = 100000
+
+// RateLimiter limits the rate of requests from each source using a token bucket.
+type RateLimiter struct {
+	rate       float64
+	burst      float64
+	maxSources int
+	now        func() time.Time
+
+	mtx     sync.Mutex
+	order   *list.List
+	sources map[string]*list.Element
+}
+
+// bucket is the token bucket of a source.
+type bucket struct {
+	source string
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter returns a RateLimiter which allows rate requests per second
+// from each source with bursts of up to burst requests.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &RateLimiter{
+		rate:       rate,
+		burst:      float64(burst),
+		maxSources: DefaultMaxSources,
+		now:        time.Now,
+		order:      list.New(),
+		sources:    make(map[string]*list.Element),
+	}
+}
+
+// Allow returns true if a request from source is allowed. If the maximum
+// number of sources are being tracked the least recently seen source is
+// evicted to track a new one.
+func (l *RateLimiter) Allow(source string) bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	now := l.now()
+	e, ok := l.sources[source]
+	if ok {
+		l.order.MoveToBack(e)
+	} else {
+		for len(l.sources) >= l.maxSources {
+			l.remove(l.order.Front())
+		}
+		e = l.order.PushBack(&bucket{source: source, tokens: l.burst, last: now})
+		l.sources[source] = e
+	}
+
+	b := e.Value.(*bucket)
+	b.tokens += now.Sub(b.last).Seconds() * l.rate
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// remove removes e.
+func (l *RateLimiter) remove(e *list.Element) {
+	l.order.Remove(e)
+	delete(l.sources, e.Value.(*bucket).source)
+}
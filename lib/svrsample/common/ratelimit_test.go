@@ -0,0 +1,63 @@
+package common
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Now()
+	l := NewRateLimiter(2, 3)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		require.True(t, l.Allow("a"), i)
+	}
+	require.False(t, l.Allow("a"))
+	require.True(t, l.Allow("b"))
+
+	now = now.Add(time.Millisecond * 500)
+	require.True(t, l.Allow("a"))
+	require.False(t, l.Allow("a"))
+}
+
+func TestRateLimiterMaxSources(t *testing.T) {
+	now := time.Now()
+	l := NewRateLimiter(1, 1)
+	l.maxSources = 2
+	l.now = func() time.Time { return now }
+
+	require.True(t, l.Allow("a"))
+	require.True(t, l.Allow("b"))
+	require.False(t, l.Allow("a"))
+
+	// The least recently seen source is evicted for a new one.
+	require.True(t, l.Allow("c"))
+	require.Len(t, l.sources, 2)
+	require.Contains(t, l.sources, "a")
+	require.Contains(t, l.sources, "c")
+
+	// b was evicted so is allowed again as a new source.
+	require.True(t, l.Allow("b"))
+	require.NotContains(t, l.sources, "a")
+}
+
+func TestRateLimiterFullTable(t *testing.T) {
+	now := time.Now()
+	l := NewRateLimiter(1, 1)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < DefaultMaxSources; i++ {
+		require.True(t, l.Allow(strconv.Itoa(i)))
+	}
+	require.Len(t, l.sources, DefaultMaxSources)
+
+	// New sources are allowed, evicting the oldest, without refilling.
+	require.True(t, l.Allow("new"))
+	require.Len(t, l.sources, DefaultMaxSources)
+	require.NotContains(t, l.sources, "0")
+	require.False(t, l.Allow("new"))
+}
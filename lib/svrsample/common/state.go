@@ -0,0 +1,48 @@
+package common
+
+import (
+	"sync"
+)
+
+// StateProvider represents something which provides the current state of
+// a game, which is requested each time a query is responded to.
+type StateProvider interface {
+	State() QueryState
+}
+
+// SyncState is a StateProvider whose state can be safely updated while
+// it is being used to respond to queries.
+type SyncState struct {
+	mtx   sync.RWMutex
+	state QueryState
+}
+
+// NewSyncState returns a new SyncState with the initial state.
+func NewSyncState(state QueryState) *SyncState {
+	return &SyncState{state: state.Clone()}
+}
+
+// State implements StateProvider. The returned state must not be modified.
+func (s *SyncState) State() QueryState {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.state
+}
+
+// UpdateState replaces the state.
+func (s *SyncState) UpdateState(state QueryState) {
+	state = state.Clone()
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.state = state
+}
+
+// Update calls f with a copy of the current state, which it can modify,
+// and replaces the state with it.
+func (s *SyncState) Update(f func(*QueryState)) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	state := s.state.Clone()
+	f(&state)
+	s.state = state
+}
@@ -0,0 +1,183 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// ValueType is the type of a DynamicValue.
+type ValueType byte
+
+// Supported value types, which match the SQP data types.
+const (
+	TypeByte ValueType = iota
+	TypeUint16
+	TypeUint32
+	TypeUint64
+	TypeString
+	TypeFloat32
+)
+
+// typeNames are the JSON names of the value types.
+var typeNames = map[ValueType]string{
+	TypeByte:    "byte",
+	TypeUint16:  "uint16",
+	TypeUint32:  "uint32",
+	TypeUint64:  "uint64",
+	TypeString:  "string",
+	TypeFloat32: "float32",
+}
+
+// String implements fmt.Stringer.
+func (t ValueType) String() string {
+	if n, ok := typeNames[t]; ok {
+		return n
+	}
+	return fmt.Sprintf("ValueType(%d)", byte(t))
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t ValueType) MarshalJSON() ([]byte, error) {
+	n, ok := typeNames[t]
+	if !ok {
+		return nil, fmt.Errorf("unknown value type %d", byte(t))
+	}
+	return json.Marshal(n)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *ValueType) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+
+	for vt, n := range typeNames {
+		if n == s {
+			*t = vt
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown value type %q", s)
+}
+
+// DynamicValue is a typed value of a rule or a player or team field.
+type DynamicValue struct {
+	Type  ValueType   `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// NewByte returns a byte DynamicValue.
+func NewByte(v byte) DynamicValue {
+	return DynamicValue{Type: TypeByte, Value: v}
+}
+
+// NewUint16 returns a uint16 DynamicValue.
+func NewUint16(v uint16) DynamicValue {
+	return DynamicValue{Type: TypeUint16, Value: v}
+}
+
+// NewUint32 returns a uint32 DynamicValue.
+func NewUint32(v uint32) DynamicValue {
+	return DynamicValue{Type: TypeUint32, Value: v}
+}
+
+// NewUint64 returns a uint64 DynamicValue.
+func NewUint64(v uint64) DynamicValue {
+	return DynamicValue{Type: TypeUint64, Value: v}
+}
+
+// NewString returns a string DynamicValue.
+func NewString(v string) DynamicValue {
+	return DynamicValue{Type: TypeString, Value: v}
+}
+
+// NewFloat32 returns a float32 DynamicValue.
+func NewFloat32(v float32) DynamicValue {
+	return DynamicValue{Type: TypeFloat32, Value: v}
+}
+
+// Validate returns an error if the Go type of the value doesn't match its type.
+func (dv DynamicValue) Validate() error {
+	var ok bool
+	switch dv.Type {
+	case TypeByte:
+		_, ok = dv.Value.(byte)
+	case TypeUint16:
+		_, ok = dv.Value.(uint16)
+	case TypeUint32:
+		_, ok = dv.Value.(uint32)
+	case TypeUint64:
+		_, ok = dv.Value.(uint64)
+	case TypeString:
+		var s string
+		if s, ok = dv.Value.(string); ok && len(s) > math.MaxUint8 {
+			return fmt.Errorf("string too long (len: %d)", len(s))
+		}
+	case TypeFloat32:
+		_, ok = dv.Value.(float32)
+	default:
+		return fmt.Errorf("unknown value type %d", byte(dv.Type))
+	}
+
+	if !ok {
+		return fmt.Errorf("invalid %s value %T", dv.Type, dv.Value)
+	}
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler converting the value to the Go type of its type.
+func (dv *DynamicValue) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Type  ValueType       `json:"type"`
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	var err error
+	switch raw.Type {
+	case TypeByte:
+		var v byte
+		err = json.Unmarshal(raw.Value, &v)
+		*dv = NewByte(v)
+	case TypeUint16:
+		var v uint16
+		err = json.Unmarshal(raw.Value, &v)
+		*dv = NewUint16(v)
+	case TypeUint32:
+		var v uint32
+		err = json.Unmarshal(raw.Value, &v)
+		*dv = NewUint32(v)
+	case TypeUint64:
+		var v uint64
+		err = json.Unmarshal(raw.Value, &v)
+		*dv = NewUint64(v)
+	case TypeString:
+		var v string
+		err = json.Unmarshal(raw.Value, &v)
+		*dv = NewString(v)
+	case TypeFloat32:
+		var v float32
+		err = json.Unmarshal(raw.Value, &v)
+		*dv = NewFloat32(v)
+	}
+	if err != nil {
+		return fmt.Errorf("%s value: %w", raw.Type, err)
+	}
+	return nil
+}
+
+// Record is the named field values of a player or team.
+type Record map[string]DynamicValue
+
+// clone returns a copy of the record.
+func (r Record) clone() Record {
+	c := make(Record, len(r))
+	for k, v := range r {
+		c[k] = v
+	}
+	return c
+}
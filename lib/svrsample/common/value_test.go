@@ -0,0 +1,59 @@
+package common
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDynamicValueJSON(t *testing.T) {
+	values := []DynamicValue{
+		NewByte(1),
+		NewUint16(2),
+		NewUint32(3),
+		NewUint64(4),
+		NewString("five"),
+		NewFloat32(6.5),
+	}
+
+	for _, v := range values {
+		t.Run(v.Type.String(), func(t *testing.T) {
+			require.NoError(t, v.Validate())
+
+			b, err := json.Marshal(v)
+			require.NoError(t, err)
+
+			var got DynamicValue
+			require.NoError(t, json.Unmarshal(b, &got))
+			require.Equal(t, v, got)
+		})
+	}
+}
+
+func TestDynamicValueInvalid(t *testing.T) {
+	var dv DynamicValue
+	require.Error(t, json.Unmarshal([]byte(`{"type": "int8", "value": 1}`), &dv))
+	require.Error(t, json.Unmarshal([]byte(`{"type": "byte", "value": 256}`), &dv))
+	require.Error(t, json.Unmarshal([]byte(`{"type": "string", "value": 1}`), &dv))
+
+	require.Error(t, DynamicValue{Type: TypeUint16, Value: 1}.Validate())
+	require.Error(t, DynamicValue{Type: ValueType(10), Value: 1}.Validate())
+}
+
+func TestQueryStateClone(t *testing.T) {
+	qs := QueryState{
+		Rules:   map[string]DynamicValue{"a": NewByte(1)},
+		Players: []Record{{"name": NewString("alice")}},
+		Metrics: []float32{1},
+	}
+
+	c := qs.Clone()
+	c.Rules["a"] = NewByte(2)
+	c.Players[0]["name"] = NewString("bob")
+	c.Metrics[0] = 2
+
+	require.Equal(t, NewByte(1), qs.Rules["a"])
+	require.Equal(t, NewString("alice"), qs.Players[0]["name"])
+	require.Equal(t, float32(1), qs.Metrics[0])
+}
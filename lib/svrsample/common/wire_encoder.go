@@ -3,6 +3,8 @@ package common
 import (
 	"bytes"
 	"encoding/binary"
+	"fmt"
+	"math"
 	"reflect"
 )
 
@@ -13,11 +15,22 @@ type WireEncoder interface {
 	Write(resp *bytes.Buffer, v interface{}) error
 }
 
+// WireMarshaler is an interface implemented by types which write their own
+// wire format, such as those containing maps or variable length values.
+type WireMarshaler interface {
+	MarshalWire(resp *bytes.Buffer, w WireEncoder) error
+}
+
 // Encoder is a struct which implements proto.WireEncoder
 type Encoder struct{}
 
 // WriteString writes a string to the provided buffer.
+// An error is returned if it is longer than the maximum length of 255.
 func (e *Encoder) WriteString(resp *bytes.Buffer, s string) error {
+	if len(s) > math.MaxUint8 {
+		return fmt.Errorf("string too long (len: %d)", len(s))
+	}
+
 	if err := binary.Write(resp, binary.BigEndian, byte(len(s))); err != nil {
 		return err
 	}
@@ -46,6 +59,13 @@ func WireWrite(resp *bytes.Buffer, w WireEncoder, data interface{}) error {
 			v = v.Elem()
 		}
 
+		if m, ok := v.Interface().(WireMarshaler); ok {
+			if err := m.MarshalWire(resp, w); err != nil {
+				return err
+			}
+			continue
+		}
+
 		switch v.Kind() {
 		case reflect.Struct:
 			if err := WireWrite(resp, w, v.Interface()); err != nil {
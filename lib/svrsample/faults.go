@@ -0,0 +1,186 @@
+package svrsample
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/multiplay/go-svrquery/lib/svrsample/common"
+)
+
+// Faults configures the faults injected into responses, which is used to test
+// how clients handle misbehaving servers. Probabilities are between 0 and 1.
+type Faults struct {
+	// Latency is the delay before each response is sent.
+	Latency time.Duration `json:"latency"`
+
+	// Jitter is the maximum random delay added to Latency.
+	Jitter time.Duration `json:"jitter"`
+
+	// Drop is the probability a response isn't sent.
+	Drop float64 `json:"drop"`
+
+	// Duplicate is the probability each packet is sent twice.
+	Duplicate float64 `json:"duplicate"`
+
+	// Reorder is the probability the packets of a multi-packet response are shuffled.
+	Reorder float64 `json:"reorder"`
+
+	// WrongChallenge is the probability a response has the wrong challenge.
+	WrongChallenge float64 `json:"wrong_challenge"`
+
+	// Truncate is the probability each packet is truncated.
+	Truncate float64 `json:"truncate"`
+
+	// GarbageDataType is the probability a response has an invalid data type.
+	GarbageDataType float64 `json:"garbage_data_type"`
+
+	// WrongPort is the probability a response is sent from a different port.
+	WrongPort float64 `json:"wrong_port"`
+
+	// Seed is the seed of the random faults, if zero a random seed is used.
+	Seed int64 `json:"seed"`
+}
+
+// Validate returns an error if f is invalid.
+func (f Faults) Validate() error {
+	if f.Latency < 0 || f.Jitter < 0 {
+		return fmt.Errorf("invalid latency %v or jitter %v", f.Latency, f.Jitter)
+	}
+
+	for name, p := range map[string]float64{
+		"drop":              f.Drop,
+		"duplicate":         f.Duplicate,
+		"reorder":           f.Reorder,
+		"wrong challenge":   f.WrongChallenge,
+		"truncate":          f.Truncate,
+		"garbage data type": f.GarbageDataType,
+		"wrong port":        f.WrongPort,
+	} {
+		if p < 0 || p > 1 {
+			return fmt.Errorf("invalid %s probability %v", name, p)
+		}
+	}
+	return nil
+}
+
+// FaultResponder is a common.MultiPacketResponder which injects faults into
+// the responses of another responder. Protocol specific faults, such as
+// WrongChallenge and GarbageDataType, are only injected if the responder
+// implements common.Corrupter. Latency, Jitter and WrongPort are injected by
+// a Server using WithFaults.
+type FaultResponder struct {
+	responder common.QueryResponder
+	faults    Faults
+
+	mtx sync.Mutex
+	rnd *rand.Rand
+}
+
+// NewFaultResponder returns a FaultResponder which injects faults into the responses of responder.
+func NewFaultResponder(responder common.QueryResponder, faults Faults) (*FaultResponder, error) {
+	if err := faults.Validate(); err != nil {
+		return nil, err
+	}
+
+	seed := faults.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	return &FaultResponder{
+		responder: responder,
+		faults:    faults,
+		rnd:       rand.New(rand.NewSource(seed)), // nolint: gosec
+	}, nil
+}
+
+// Respond implements common.QueryResponder. An error is returned if the
+// response requires multiple packets, in which case RespondPackets must be used.
+func (r *FaultResponder) Respond(clientAddress string, buf []byte) ([]byte, error) {
+	pkts, err := r.RespondPackets(clientAddress, buf)
+	switch {
+	case err != nil:
+		return nil, err
+	case len(pkts) == 0:
+		return nil, nil
+	case len(pkts) != 1:
+		return nil, fmt.Errorf("response requires %d packets", len(pkts))
+	}
+	return pkts[0], nil
+}
+
+// RespondPackets implements common.MultiPacketResponder.
+func (r *FaultResponder) RespondPackets(clientAddress string, buf []byte) ([][]byte, error) {
+	pkts, err := respondPackets(r.responder, clientAddress, buf)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.chance(r.faults.Drop) {
+		return nil, nil
+	}
+
+	if c, ok := r.responder.(common.Corrupter); ok {
+		if r.chance(r.faults.WrongChallenge) {
+			c.CorruptChallenge(buf, pkts)
+		}
+		if r.chance(r.faults.GarbageDataType) {
+			c.CorruptDataType(buf, pkts)
+		}
+	}
+
+	for i, p := range pkts {
+		if len(p) > 1 && r.chance(r.faults.Truncate) {
+			pkts[i] = p[:1+r.rnd.Intn(len(p)-1)]
+		}
+	}
+
+	if len(pkts) > 1 && r.chance(r.faults.Reorder) {
+		r.rnd.Shuffle(len(pkts), func(i, j int) {
+			pkts[i], pkts[j] = pkts[j], pkts[i]
+		})
+	}
+
+	res := make([][]byte, 0, len(pkts))
+	for _, p := range pkts {
+		res = append(res, p)
+		if r.chance(r.faults.Duplicate) {
+			res = append(res, p)
+		}
+	}
+	return res, nil
+}
+
+// VerifiesAddress implements common.AddressVerifier using the wrapped responder, if it supports it.
+func (r *FaultResponder) VerifiesAddress(req []byte) bool {
+	v, ok := r.responder.(common.AddressVerifier)
+	return ok && v.VerifiesAddress(req)
+}
+
+// delay returns the delay before sending a response.
+func (r *FaultResponder) delay() time.Duration {
+	d := r.faults.Latency
+	if r.faults.Jitter > 0 {
+		r.mtx.Lock()
+		d += time.Duration(r.rnd.Int63n(int64(r.faults.Jitter) + 1))
+		r.mtx.Unlock()
+	}
+	return d
+}
+
+// wrongPort returns true if a response should be sent from a different port.
+func (r *FaultResponder) wrongPort() bool {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.chance(r.faults.WrongPort)
+}
+
+// chance returns true with probability p. The lock must be held.
+func (r *FaultResponder) chance(p float64) bool {
+	return p > 0 && r.rnd.Float64() < p
+}
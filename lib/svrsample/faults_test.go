@@ -0,0 +1,149 @@
+package svrsample
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/multiplay/go-svrquery/lib/svrquery"
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+	sqpclient "github.com/multiplay/go-svrquery/lib/svrquery/protocol/sqp"
+	"github.com/multiplay/go-svrquery/lib/svrsample/common"
+	"github.com/multiplay/go-svrquery/lib/svrsample/protocol/sqp"
+	"github.com/stretchr/testify/require"
+)
+
+// faultState is the state used to test faults, whose rules have a data type.
+var faultState = common.QueryState{
+	CurrentPlayers: 1,
+	MaxPlayers:     2,
+	Rules:          map[string]common.DynamicValue{"mode": common.NewString("ranked")},
+}
+
+// newFaultServer starts a sqp server with faults returning its address.
+func newFaultServer(t *testing.T, faults Faults, options ...sqp.Option) string {
+	t.Helper()
+	responder, err := sqp.NewQueryResponder(faultState, options...)
+	require.NoError(t, err)
+
+	s, err := NewServer("127.0.0.1:0", responder, WithFaults(faults))
+	require.NoError(t, err)
+	require.NoError(t, s.Listen())
+	go s.Serve(context.Background())                       // nolint: errcheck
+	t.Cleanup(func() { s.Shutdown(context.Background()) }) // nolint: errcheck
+
+	return s.Addr().String()
+}
+
+func TestServerFaults(t *testing.T) {
+	testCases := []struct {
+		name    string
+		faults  Faults
+		options []sqp.Option
+		err     error
+	}{
+		{
+			name: "none",
+		},
+		{
+			name:   "drop",
+			faults: Faults{Drop: 1},
+			err:    protocol.ErrTimeout,
+		},
+		{
+			name:   "wrong challenge",
+			faults: Faults{WrongChallenge: 1},
+			err:    protocol.ErrChallenge,
+		},
+		{
+			name:   "garbage data type",
+			faults: Faults{GarbageDataType: 1},
+			err:    protocol.ErrMalformed,
+		},
+		{
+			name:   "wrong port",
+			faults: Faults{WrongPort: 1},
+			err:    protocol.ErrTimeout,
+		},
+		{
+			name:    "reorder",
+			faults:  Faults{Reorder: 1, Seed: 1},
+			options: []sqp.Option{sqp.WithMaxPacketSize(16)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			addr := newFaultServer(t, tc.faults, tc.options...)
+			c, err := svrquery.NewClient("sqp", addr,
+				svrquery.WithTimeout(time.Millisecond*200),
+				svrquery.WithArg(sqpclient.ChunksArg, "info+rules"),
+			)
+			require.NoError(t, err)
+			defer c.Close()
+
+			resp, err := c.Query()
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "ranked", resp.(*sqpclient.QueryResponse).ServerRules.Rules["mode"].String())
+		})
+	}
+}
+
+func TestServerFaultsDropStats(t *testing.T) {
+	responder, err := sqp.NewQueryResponder(faultState)
+	require.NoError(t, err)
+
+	s, err := NewServer("127.0.0.1:0", responder, WithFaults(Faults{Drop: 1}))
+	require.NoError(t, err)
+	require.NoError(t, s.Listen())
+	go s.Serve(context.Background())                       // nolint: errcheck
+	t.Cleanup(func() { s.Shutdown(context.Background()) }) // nolint: errcheck
+
+	c, err := svrquery.NewClient("sqp", s.Addr().String(), svrquery.WithTimeout(time.Millisecond*50))
+	require.NoError(t, err)
+	defer c.Close()
+
+	_, err = c.Query()
+	require.ErrorIs(t, err, protocol.ErrTimeout)
+	require.Eventually(t, func() bool {
+		return s.Stats() == Stats{Received: 1, Dropped: 1}
+	}, time.Second, time.Millisecond*10, "%+v", s.Stats())
+}
+
+func TestServerFaultsLatency(t *testing.T) {
+	addr := newFaultServer(t, Faults{Latency: time.Millisecond * 50, Jitter: time.Millisecond * 10})
+	c, err := svrquery.NewClient("sqp", addr)
+	require.NoError(t, err)
+	defer c.Close()
+
+	rtt, err := c.Ping()
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, rtt, time.Millisecond*50)
+}
+
+func TestFaultResponder(t *testing.T) {
+	responder, err := sqp.NewQueryResponder(faultState)
+	require.NoError(t, err)
+
+	r, err := NewFaultResponder(responder, Faults{Duplicate: 1, Truncate: 1, Seed: 1})
+	require.NoError(t, err)
+
+	pkts, err := r.RespondPackets("addr", []byte{0, 0, 0, 0, 0})
+	require.NoError(t, err)
+	require.Len(t, pkts, 2)
+	require.Equal(t, pkts[0], pkts[1])
+	require.Less(t, len(pkts[0]), 5)
+	require.NotEmpty(t, pkts[0])
+
+	_, err = r.Respond("addr", []byte{0, 0, 0, 0, 0})
+	require.EqualError(t, err, "response requires 2 packets")
+
+	for _, f := range []Faults{{Drop: -0.1}, {Truncate: 1.1}, {Latency: -1}} {
+		_, err = NewFaultResponder(responder, f)
+		require.Error(t, err)
+	}
+}
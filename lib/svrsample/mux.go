@@ -0,0 +1,123 @@
+package svrsample
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+
+	"github.com/multiplay/go-svrquery/lib/svrsample/common"
+)
+
+var (
+	// ErrNoMatch is returned by Mux when no responder matches a request.
+	// Server counts these requests as denied rather than as errors.
+	ErrNoMatch = errors.New("no responder matches request")
+)
+
+// Matcher represents a detector of the requests of a protocol, see common.Matcher.
+type Matcher = common.Matcher
+
+// MatcherFunc is an adapter to allow the use of ordinary functions as a Matcher.
+type MatcherFunc = common.MatcherFunc
+
+// PrefixMatcher returns a Matcher which matches requests starting with any of prefixes.
+func PrefixMatcher(prefixes ...[]byte) Matcher {
+	return MatcherFunc(func(buf []byte) bool {
+		for _, p := range prefixes {
+			if bytes.HasPrefix(buf, p) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// route is a responder and the Matcher of its requests.
+type route struct {
+	matcher   Matcher
+	responder common.QueryResponder
+}
+
+// Mux is a common.MultiPacketResponder which dispatches each request to the
+// first registered responder whose Matcher matches it, so multiple protocols
+// can be served on a single port.
+type Mux struct {
+	mtx    sync.RWMutex
+	routes []route
+}
+
+// NewMux returns a new Mux with no responders.
+func NewMux() *Mux {
+	return &Mux{}
+}
+
+// Handle registers responder for requests matched by matcher. Matchers are
+// checked in the order they are registered.
+func (m *Mux) Handle(matcher Matcher, responder common.QueryResponder) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.routes = append(m.routes, route{matcher: matcher, responder: responder})
+}
+
+// Respond implements common.QueryResponder.
+func (m *Mux) Respond(clientAddress string, buf []byte) ([]byte, error) {
+	r, err := m.match(buf)
+	if err != nil {
+		return nil, err
+	}
+	return r.Respond(clientAddress, buf)
+}
+
+// RespondPackets implements common.MultiPacketResponder.
+func (m *Mux) RespondPackets(clientAddress string, buf []byte) ([][]byte, error) {
+	r, err := m.match(buf)
+	if err != nil {
+		return nil, err
+	}
+	return respondPackets(r, clientAddress, buf)
+}
+
+// match returns the responder of the first Matcher which matches buf.
+func (m *Mux) match(buf []byte) (common.QueryResponder, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	for _, r := range m.routes {
+		if r.matcher.Match(buf) {
+			return r.responder, nil
+		}
+	}
+	return nil, ErrNoMatch
+}
+
+// CorruptChallenge implements common.Corrupter using the matched responder, if it supports it.
+func (m *Mux) CorruptChallenge(req []byte, pkts [][]byte) bool {
+	c, ok := m.corrupter(req)
+	return ok && c.CorruptChallenge(req, pkts)
+}
+
+// CorruptDataType implements common.Corrupter using the matched responder, if it supports it.
+func (m *Mux) CorruptDataType(req []byte, pkts [][]byte) bool {
+	c, ok := m.corrupter(req)
+	return ok && c.CorruptDataType(req, pkts)
+}
+
+// VerifiesAddress implements common.AddressVerifier using the matched responder, if it supports it.
+func (m *Mux) VerifiesAddress(req []byte) bool {
+	r, err := m.match(req)
+	if err != nil {
+		return false
+	}
+	v, ok := r.(common.AddressVerifier)
+	return ok && v.VerifiesAddress(req)
+}
+
+// corrupter returns the matched responder of req if it's a common.Corrupter.
+func (m *Mux) corrupter(req []byte) (common.Corrupter, bool) {
+	r, err := m.match(req)
+	if err != nil {
+		return nil, false
+	}
+	c, ok := r.(common.Corrupter)
+	return c, ok
+}
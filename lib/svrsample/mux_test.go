@@ -0,0 +1,111 @@
+package svrsample
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/multiplay/go-svrquery/lib/svrquery"
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+	"github.com/multiplay/go-svrquery/lib/svrsample/common"
+	"github.com/stretchr/testify/require"
+)
+
+// echoResponder responds with the request.
+type echoResponder struct{}
+
+func (echoResponder) Respond(clientAddress string, buf []byte) ([]byte, error) {
+	return append([]byte(nil), buf...), nil
+}
+
+func newTestMux(t *testing.T) *Mux {
+	t.Helper()
+	r, err := GetResponder("sqp", common.QueryState{CurrentPlayers: 1, MaxPlayers: 2})
+	require.NoError(t, err)
+	m, err := GetMatcher("sqp")
+	require.NoError(t, err)
+
+	mux := NewMux()
+	mux.Handle(m, r)
+	mux.Handle(PrefixMatcher([]byte{0xff, 0xff, 0xff, 0xff, 'M'}, []byte{0xff, 0xff, 0xff, 0xff, 'T'}), echoResponder{})
+	return mux
+}
+
+func TestMux(t *testing.T) {
+	s, err := NewServer("127.0.0.1:0", newTestMux(t))
+	require.NoError(t, err)
+	require.NoError(t, s.Listen())
+	go s.Serve(context.Background())       // nolint: errcheck
+	defer s.Shutdown(context.Background()) // nolint: errcheck
+
+	c, err := svrquery.NewClient("sqp", s.Addr().String())
+	require.NoError(t, err)
+	defer c.Close()
+	r, err := c.Query()
+	require.NoError(t, err)
+	require.Equal(t, int64(1), r.NumClients())
+
+	conn, err := net.Dial("udp", s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.SetDeadline(time.Now().Add(time.Second)))
+
+	for _, req := range [][]byte{{0xff, 0xff, 0xff, 0xff, 'M', 1}, {0xff, 0xff, 0xff, 0xff, 'T'}} {
+		_, err = conn.Write(req)
+		require.NoError(t, err)
+		buf := make([]byte, 16)
+		n, err := conn.Read(buf)
+		require.NoError(t, err)
+		require.Equal(t, req, buf[:n])
+	}
+}
+
+func TestMuxNoMatch(t *testing.T) {
+	mux := newTestMux(t)
+	_, err := mux.Respond("addr", []byte{0xff, 0xff, 0xff, 0xff, 'X'})
+	require.ErrorIs(t, err, ErrNoMatch)
+	_, err = mux.RespondPackets("addr", nil)
+	require.ErrorIs(t, err, ErrNoMatch)
+
+	_, err = GetMatcher("foo")
+	require.ErrorIs(t, err, ErrProtoNotSupported)
+}
+
+func TestMuxNoMatchServer(t *testing.T) {
+	errs := &errorRecorder{}
+	s, err := NewServer("127.0.0.1:0", newTestMux(t), WithErrorHandler(errs.handle))
+	require.NoError(t, err)
+	require.NoError(t, s.Listen())
+	go s.Serve(context.Background())       // nolint: errcheck
+	defer s.Shutdown(context.Background()) // nolint: errcheck
+
+	conn, err := net.Dial("udp", s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	for i := 0; i < 3; i++ {
+		_, err = conn.Write([]byte("noise"))
+		require.NoError(t, err)
+	}
+
+	require.Eventually(t, func() bool {
+		return s.Stats().Denied == 3
+	}, time.Second, time.Millisecond*10)
+	require.Zero(t, s.Stats().Errors)
+	require.Zero(t, errs.len())
+}
+
+func TestMuxFaults(t *testing.T) {
+	s, err := NewServer("127.0.0.1:0", newTestMux(t), WithFaults(Faults{WrongChallenge: 1}))
+	require.NoError(t, err)
+	require.NoError(t, s.Listen())
+	go s.Serve(context.Background())       // nolint: errcheck
+	defer s.Shutdown(context.Background()) // nolint: errcheck
+
+	// Protocol specific faults are injected by the matched responder.
+	c, err := svrquery.NewClient("sqp", s.Addr().String())
+	require.NoError(t, err)
+	defer c.Close()
+	_, err = c.Query()
+	require.ErrorIs(t, err, protocol.ErrChallenge)
+}
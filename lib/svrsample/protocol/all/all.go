@@ -0,0 +1,7 @@
+// Package all provides access to all available sample responders.
+package all
+
+import (
+	// Register all known protocols
+	_ "github.com/multiplay/go-svrquery/lib/svrsample/protocol/sqp"
+)
@@ -0,0 +1,67 @@
+// Package protocol provides the registry of the protocols of sample responders.
+package protocol
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/multiplay/go-svrquery/lib/svrsample/common"
+)
+
+// Creator is a function which returns a QueryResponder which responds with
+// the current state of provider.
+type Creator func(provider common.StateProvider) (common.QueryResponder, error)
+
+// entry is a registered protocol.
+type entry struct {
+	creator Creator
+	matcher common.Matcher
+}
+
+var (
+	registry = make(map[string]entry)
+)
+
+// MustRegister registers a protocol, with the same name as its query protocol,
+// and the matcher of its requests.
+// Panics if the name is a duplicate.
+func MustRegister(name string, f Creator, m common.Matcher) {
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("%s is already in registry", name))
+	}
+	registry[name] = entry{creator: f, matcher: m}
+}
+
+// Get returns the creator of a protocol.
+func Get(name string) (Creator, error) {
+	e, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown protocol %q", name)
+	}
+	return e.creator, nil
+}
+
+// GetMatcher returns the matcher of the requests of a protocol.
+func GetMatcher(name string) (common.Matcher, error) {
+	e, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown protocol %q", name)
+	}
+	return e.matcher, nil
+}
+
+// Supported returns true if protocol name is supported.
+func Supported(name string) bool {
+	_, ok := registry[name]
+	return ok
+}
+
+// Names returns the names of the registered protocols in order.
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,96 @@
+package sqp
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// challenge is a challenge issued to a client.
+type challenge struct {
+	addr    string
+	value   uint32
+	expires time.Time
+}
+
+// challengeStore stores the challenges issued to clients until they are
+// used or expire, evicting the oldest if it is full.
+type challengeStore struct {
+	mtx     sync.Mutex
+	ttl     time.Duration
+	max     int
+	now     func() time.Time
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+// newChallengeStore returns a new challengeStore.
+func newChallengeStore(ttl time.Duration, max int) *challengeStore {
+	return &challengeStore{
+		ttl:     ttl,
+		max:     max,
+		now:     time.Now,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// store stores the challenge issued to addr, replacing any previous challenge.
+func (s *challengeStore) store(addr string, value uint32) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	now := s.now()
+	s.expire(now)
+
+	c := challenge{addr: addr, value: value, expires: now.Add(s.ttl)}
+	if e, ok := s.entries[addr]; ok {
+		e.Value = c
+		s.order.MoveToBack(e)
+		return
+	}
+
+	for len(s.entries) >= s.max {
+		s.remove(s.order.Front())
+	}
+	s.entries[addr] = s.order.PushBack(c)
+}
+
+// take removes and returns the challenge issued to addr, returning false
+// if there isn't one or it has expired.
+func (s *challengeStore) take(addr string) (uint32, bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	e, ok := s.entries[addr]
+	if !ok {
+		return 0, false
+	}
+	s.remove(e)
+
+	c := e.Value.(challenge)
+	if !s.now().Before(c.expires) {
+		return 0, false
+	}
+	return c.value, true
+}
+
+// len returns the number of stored challenges.
+func (s *challengeStore) len() int {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return len(s.entries)
+}
+
+// expire removes the expired challenges, which are the oldest as all have the same TTL.
+func (s *challengeStore) expire(now time.Time) {
+	for e := s.order.Front(); e != nil && !now.Before(e.Value.(challenge).expires); e = s.order.Front() {
+		s.remove(e)
+	}
+}
+
+// remove removes e.
+func (s *challengeStore) remove(e *list.Element) {
+	s.order.Remove(e)
+	delete(s.entries, e.Value.(challenge).addr)
+}
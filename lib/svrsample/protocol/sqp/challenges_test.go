@@ -0,0 +1,41 @@
+package sqp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChallengeStore(t *testing.T) {
+	now := time.Now()
+	s := newChallengeStore(time.Second, 2)
+	s.now = func() time.Time { return now }
+
+	s.store("a", 1)
+	s.store("b", 2)
+	s.store("a", 3)
+	require.Equal(t, 2, s.len())
+
+	// The oldest challenge, now b, is evicted.
+	s.store("c", 4)
+	require.Equal(t, 2, s.len())
+	_, ok := s.take("b")
+	require.False(t, ok)
+
+	v, ok := s.take("a")
+	require.True(t, ok)
+	require.Equal(t, uint32(3), v)
+	_, ok = s.take("a")
+	require.False(t, ok)
+
+	// Expired challenges are not returned and are removed.
+	now = now.Add(time.Second)
+	_, ok = s.take("c")
+	require.False(t, ok)
+
+	s.store("d", 5)
+	now = now.Add(time.Second)
+	s.store("e", 6)
+	require.Equal(t, 1, s.len())
+}
@@ -0,0 +1,25 @@
+package sqp
+
+import (
+	"time"
+)
+
+const (
+	// DefaultChallengeTTL is the default time a challenge is valid for after being issued.
+	DefaultChallengeTTL = 5 * time.Second
+
+	// DefaultMaxChallenges is the default maximum number of outstanding challenges.
+	DefaultMaxChallenges = 100000
+
+	// DefaultMaxPacketSize is the default maximum size of a response packet (MTU 1500 - UDP+IP header size).
+	DefaultMaxPacketSize = 1472
+
+	// MaxMetrics is the maximum number of metrics supported in a response.
+	MaxMetrics = 25
+
+	// queryHeaderLength is the length of the header of each query response packet.
+	queryHeaderLength = 11
+
+	// maxPackets is the maximum number of packets in a query response.
+	maxPackets = 256
+)
@@ -0,0 +1,98 @@
+package sqp
+
+import (
+	"encoding/binary"
+)
+
+// invalidDataType is the data type written by CorruptDataType.
+const invalidDataType = 0xff
+
+// CorruptChallenge implements common.Corrupter changing the challenge of query responses.
+func (q *QueryResponder) CorruptChallenge(req []byte, pkts [][]byte) bool {
+	if !isQuery(req) || len(pkts) == 0 {
+		return false
+	}
+
+	for _, p := range pkts {
+		if len(p) < queryHeaderLength {
+			return false
+		}
+	}
+
+	for _, p := range pkts {
+		binary.BigEndian.PutUint32(p[1:5], ^binary.BigEndian.Uint32(p[1:5]))
+	}
+	return true
+}
+
+// CorruptDataType implements common.Corrupter changing the data type of the
+// first rule, player field or team field of query responses.
+func (q *QueryResponder) CorruptDataType(req []byte, pkts [][]byte) bool {
+	if !isQuery(req) || len(req) < 8 {
+		return false
+	}
+
+	var payload []byte
+	for _, p := range pkts {
+		if len(p) < queryHeaderLength {
+			return false
+		}
+		payload = append(payload, p[queryHeaderLength:]...)
+	}
+
+	off, ok := dataTypeOffset(req[7], payload)
+	if !ok {
+		return false
+	}
+
+	// Find the packet containing the data type, which are in payload order.
+	for _, p := range pkts {
+		if n := len(p) - queryHeaderLength; off >= n {
+			off -= n
+			continue
+		}
+		p[queryHeaderLength+off] = invalidDataType
+		return true
+	}
+	return false
+}
+
+// dataTypeOffset returns the offset in payload of the first data type of
+// the requested chunks, returning false if there is none.
+func dataTypeOffset(requestedChunks byte, payload []byte) (int, bool) {
+	var off int
+	for _, chunk := range []byte{0x1, 0x2, 0x4, 0x8, 0x10} {
+		if requestedChunks&chunk == 0 {
+			continue
+		} else if off+4 > len(payload) {
+			return 0, false
+		}
+
+		size := int(binary.BigEndian.Uint32(payload[off:]))
+		start := off + 4
+		off = start + size
+		if off > len(payload) || size == 0 {
+			continue
+		}
+
+		var pos int
+		switch chunk {
+		case 0x2:
+			// Name followed by type.
+			pos = start
+		case 0x4, 0x8:
+			// Count and field count followed by the name and type of the first field.
+			if size < 4 || binary.BigEndian.Uint16(payload[start:]) == 0 {
+				continue
+			}
+			pos = start + 3
+		default:
+			continue
+		}
+
+		if pos = pos + 1 + int(payload[pos]); pos < off {
+			return pos, true
+		}
+	}
+	return 0, false
+}
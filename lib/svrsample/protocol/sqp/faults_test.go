@@ -0,0 +1,54 @@
+package sqp
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/multiplay/go-svrquery/lib/svrsample/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCorrupt(t *testing.T) {
+	addr := "client-addr:65534"
+	state := common.QueryState{
+		CurrentPlayers: 1,
+		MaxPlayers:     2,
+		Players:        []common.Record{{"name": common.NewString("alice")}},
+	}
+	q, err := NewQueryResponder(state, WithMaxPacketSize(16))
+	require.NoError(t, err)
+
+	challenge := []byte{0, 0, 0, 0, 0}
+	resp, err := q.Respond(addr, challenge)
+	require.NoError(t, err)
+	require.False(t, q.CorruptChallenge(challenge, [][]byte{resp}))
+	require.False(t, q.CorruptDataType(challenge, [][]byte{resp}))
+
+	req := bytes.Join([][]byte{{1}, resp[1:5], {0, 1}, {0x5}}, nil)
+	pkts, err := q.RespondPackets(addr, req)
+	require.NoError(t, err)
+	require.Greater(t, len(pkts), 2)
+
+	// The field type follows the server info, players chunk length, count,
+	// field count and name, which is split across packets.
+	payload := func() []byte {
+		var b []byte
+		for _, p := range pkts {
+			b = append(b, p[queryHeaderLength:]...)
+		}
+		return b
+	}
+	off := 4 + 10 + 4 + 2 + 1 + 5
+	require.Equal(t, byte(common.TypeString), payload()[off])
+	require.True(t, q.CorruptDataType(req, pkts))
+	require.Equal(t, byte(invalidDataType), payload()[off])
+
+	require.True(t, q.CorruptChallenge(req, pkts))
+	for _, p := range pkts {
+		require.NotEqual(t, req[1:5], p[1:5])
+	}
+
+	// No data types to corrupt.
+	req[7] = 0x1
+	require.False(t, q.CorruptDataType(req, pkts))
+}
@@ -0,0 +1,130 @@
+package sqp
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/multiplay/go-svrquery/lib/svrsample/common"
+)
+
+// Field describes a field of the records of a PlayerInfo or TeamInfo chunk.
+type Field struct {
+	Name string
+	Type common.ValueType
+}
+
+// Records holds the PlayerInfo or TeamInfo chunk data, which is a header
+// describing the fields followed by the field values of each record.
+type Records struct {
+	Fields []Field
+	Values [][]common.DynamicValue
+}
+
+// Size returns the number of bytes QueryResponder will use on the wire.
+func (r Records) Size() uint32 {
+	size := uint32(2) // Count
+	if len(r.Values) == 0 {
+		return size
+	}
+
+	size++ // Field count
+	for _, f := range r.Fields {
+		size += uint32(len(f.Name)+1) + 1 // Name and type
+	}
+
+	for _, vs := range r.Values {
+		for _, v := range vs {
+			size += valueSize(v)
+		}
+	}
+	return size
+}
+
+// MarshalWire implements common.WireMarshaler.
+func (r Records) MarshalWire(resp *bytes.Buffer, w common.WireEncoder) error {
+	if err := w.Write(resp, uint16(len(r.Values))); err != nil {
+		return err
+	} else if len(r.Values) == 0 {
+		return nil
+	}
+
+	if err := w.Write(resp, byte(len(r.Fields))); err != nil {
+		return err
+	}
+
+	for _, f := range r.Fields {
+		if err := w.WriteString(resp, f.Name); err != nil {
+			return err
+		} else if err = w.Write(resp, byte(f.Type)); err != nil {
+			return err
+		}
+	}
+
+	for i, vs := range r.Values {
+		for j, v := range vs {
+			if err := writeValue(resp, w, v); err != nil {
+				return fmt.Errorf("record %d field %q: %w", i, r.Fields[j].Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// PlayerInfoFromQueryState converts players in common.QueryState to Records.
+func PlayerInfoFromQueryState(qs common.QueryState) (*Records, error) {
+	r, err := newRecords(qs.Players)
+	if err != nil {
+		return nil, fmt.Errorf("players: %w", err)
+	}
+	return r, nil
+}
+
+// TeamInfoFromQueryState converts teams in common.QueryState to Records.
+func TeamInfoFromQueryState(qs common.QueryState) (*Records, error) {
+	r, err := newRecords(qs.Teams)
+	if err != nil {
+		return nil, fmt.Errorf("teams: %w", err)
+	}
+	return r, nil
+}
+
+// newRecords returns the Records of records, whose fields, ordered by name,
+// are those of the first record. All records must have the same fields and types.
+func newRecords(records []common.Record) (*Records, error) {
+	r := &Records{}
+	if len(records) == 0 {
+		return r, nil
+	} else if len(records) > math.MaxUint16 {
+		return nil, fmt.Errorf("too many records %d", len(records))
+	} else if len(records[0]) == 0 || len(records[0]) > math.MaxUint8 {
+		return nil, fmt.Errorf("invalid field count %d", len(records[0]))
+	}
+
+	for k, v := range records[0] {
+		r.Fields = append(r.Fields, Field{Name: k, Type: v.Type})
+	}
+	sort.Slice(r.Fields, func(i, j int) bool {
+		return r.Fields[i].Name < r.Fields[j].Name
+	})
+
+	r.Values = make([][]common.DynamicValue, len(records))
+	for i, rec := range records {
+		if len(rec) != len(r.Fields) {
+			return nil, fmt.Errorf("record %d has %d fields (expected %d)", i, len(rec), len(r.Fields))
+		}
+
+		r.Values[i] = make([]common.DynamicValue, len(r.Fields))
+		for j, f := range r.Fields {
+			v, ok := rec[f.Name]
+			if !ok {
+				return nil, fmt.Errorf("record %d missing field %q", i, f.Name)
+			} else if v.Type != f.Type {
+				return nil, fmt.Errorf("record %d field %q has type %s (expected %s)", i, f.Name, v.Type, f.Type)
+			}
+			r.Values[i][j] = v
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,15 @@
+package sqp
+
+import (
+	"github.com/multiplay/go-svrquery/lib/svrsample/common"
+	"github.com/multiplay/go-svrquery/lib/svrsample/protocol"
+)
+
+func init() {
+	protocol.MustRegister("sqp", newCreator, common.MatcherFunc(Match))
+}
+
+// newCreator returns a QueryResponder with the default options.
+func newCreator(provider common.StateProvider) (common.QueryResponder, error) {
+	return NewQueryResponderWithProvider(provider)
+}
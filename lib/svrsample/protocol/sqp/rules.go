@@ -0,0 +1,51 @@
+package sqp
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/multiplay/go-svrquery/lib/svrsample/common"
+)
+
+// ServerRules holds the ServerRules chunk data.
+type ServerRules struct {
+	Rules map[string]common.DynamicValue
+}
+
+// Size returns the number of bytes QueryResponder will use on the wire.
+func (sr ServerRules) Size() uint32 {
+	var size uint32
+	for k, v := range sr.Rules {
+		size += uint32(len(k)+1) + // Name
+			1 + // Type
+			valueSize(v)
+	}
+	return size
+}
+
+// MarshalWire implements common.WireMarshaler writing the rules ordered by name.
+func (sr ServerRules) MarshalWire(resp *bytes.Buffer, w common.WireEncoder) error {
+	names := make([]string, 0, len(sr.Rules))
+	for k := range sr.Rules {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	for _, k := range names {
+		v := sr.Rules[k]
+		if err := w.WriteString(resp, k); err != nil {
+			return err
+		} else if err = w.Write(resp, byte(v.Type)); err != nil {
+			return err
+		} else if err = writeValue(resp, w, v); err != nil {
+			return fmt.Errorf("rule %q: %w", k, err)
+		}
+	}
+	return nil
+}
+
+// ServerRulesFromQueryState converts rules in common.QueryState to ServerRules.
+func ServerRulesFromQueryState(qs common.QueryState) *ServerRules {
+	return &ServerRules{Rules: qs.Rules}
+}
@@ -35,6 +35,7 @@ func ServerInfoFromQueryState(qs common.QueryState) *ServerInfo {
 		MaxPlayers:     uint16(qs.MaxPlayers),
 		ServerName:     qs.ServerName,
 		GameType:       qs.GameType,
+		BuildID:        qs.BuildID,
 		GameMap:        qs.Map,
 		Port:           qs.Port,
 	}
@@ -5,17 +5,24 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"math"
 	"math/rand"
-	"sync"
+	"time"
 
 	"github.com/multiplay/go-svrquery/lib/svrsample/common"
 )
 
+// Option represents a QueryResponder option.
+type Option func(*QueryResponder) error
+
 // QueryResponder responds to queries
 type QueryResponder struct {
-	challenges sync.Map
-	enc        *common.Encoder
-	state      common.QueryState
+	challenges    *challengeStore
+	challengeTTL  time.Duration
+	maxChallenges int
+	enc           *common.Encoder
+	state         common.StateProvider
+	maxPacketSize int
 }
 
 // challengeWireFormat describes the format of an SQP challenge response
@@ -24,35 +31,117 @@ type challengeWireFormat struct {
 	Challenge uint32
 }
 
-// queryWireFormat describes the format of an SQP query response
-type queryWireFormat struct {
+// queryHeaderWireFormat describes the format of the header of each SQP query response packet
+type queryHeaderWireFormat struct {
 	Header           byte
 	Challenge        uint32
 	SQPVersion       uint16
 	CurrentPacketNum byte
 	LastPacketNum    byte
 	PayloadLength    uint16
-	ServerInfoLength *uint32
-	ServerInfo       *ServerInfo
-	MetricsLength    *uint32
-	Metrics          *Metrics
+}
+
+// queryPayloadWireFormat describes the format of an SQP query response payload,
+// which is split across packets if required
+type queryPayloadWireFormat struct {
+	ServerInfoLength  *uint32
+	ServerInfo        *ServerInfo
+	ServerRulesLength *uint32
+	ServerRules       *ServerRules
+	PlayerInfoLength  *uint32
+	PlayerInfo        *Records
+	TeamInfoLength    *uint32
+	TeamInfo          *Records
+	MetricsLength     *uint32
+	Metrics           *Metrics
+}
+
+// WithMaxPacketSize sets the maximum size of a response packet, larger
+// responses are split across multiple packets.
+func WithMaxPacketSize(size int) Option {
+	return func(q *QueryResponder) error {
+		if size <= queryHeaderLength || size > math.MaxUint16 {
+			return fmt.Errorf("invalid max packet size %d", size)
+		}
+		q.maxPacketSize = size
+		return nil
+	}
+}
+
+// WithChallengeTTL sets how long a challenge is valid for after being issued.
+func WithChallengeTTL(ttl time.Duration) Option {
+	return func(q *QueryResponder) error {
+		if ttl <= 0 {
+			return fmt.Errorf("invalid challenge ttl %v", ttl)
+		}
+		q.challengeTTL = ttl
+		return nil
+	}
+}
+
+// WithMaxChallenges sets the maximum number of outstanding challenges,
+// beyond which the oldest are discarded.
+func WithMaxChallenges(n int) Option {
+	return func(q *QueryResponder) error {
+		if n < 1 {
+			return fmt.Errorf("invalid max challenges %d", n)
+		}
+		q.maxChallenges = n
+		return nil
+	}
 }
 
 // NewQueryResponder returns creates a new responder capable of responding
-// to SQP-formatted queries.
-func NewQueryResponder(state common.QueryState) (*QueryResponder, error) {
+// to SQP-formatted queries with a fixed state.
+func NewQueryResponder(state common.QueryState, options ...Option) (*QueryResponder, error) {
+	return NewQueryResponderWithProvider(common.NewSyncState(state), options...)
+}
+
+// NewQueryResponderWithProvider creates a new responder capable of responding
+// to SQP-formatted queries with the current state of provider.
+func NewQueryResponderWithProvider(provider common.StateProvider, options ...Option) (*QueryResponder, error) {
 	q := &QueryResponder{
-		enc:   &common.Encoder{},
-		state: state,
+		enc:           &common.Encoder{},
+		state:         provider,
+		maxPacketSize: DefaultMaxPacketSize,
+		challengeTTL:  DefaultChallengeTTL,
+		maxChallenges: DefaultMaxChallenges,
+	}
+
+	for _, o := range options {
+		if err := o(q); err != nil {
+			return nil, err
+		}
 	}
+	q.challenges = newChallengeStore(q.challengeTTL, q.maxChallenges)
 	return q, nil
 }
 
 // Respond writes a query response to the requester in the SQP wire protocol.
+// An error is returned if the response requires multiple packets, in which
+// case RespondPackets must be used.
 func (q *QueryResponder) Respond(clientAddress string, buf []byte) ([]byte, error) {
+	pkts, err := q.RespondPackets(clientAddress, buf)
+	if err != nil {
+		return nil, err
+	} else if len(pkts) != 1 {
+		return nil, fmt.Errorf("response requires %d packets", len(pkts))
+	}
+	return pkts[0], nil
+}
+
+// RespondPackets implements common.MultiPacketResponder.
+func (q *QueryResponder) RespondPackets(clientAddress string, buf []byte) ([][]byte, error) {
 	switch {
 	case isChallenge(buf):
-		return q.handleChallenge(clientAddress)
+		resp, err := q.handleChallenge(clientAddress)
+		if err != nil {
+			return nil, err
+		} else if len(resp) > len(buf) {
+			// Challenges are unverified so must not be amplified.
+			return nil, fmt.Errorf("challenge response larger than request (len: %d)", len(buf))
+		}
+		return [][]byte{resp}, nil
 
 	case isQuery(buf):
 		return q.handleQuery(clientAddress, buf)
@@ -61,20 +150,31 @@ func (q *QueryResponder) Respond(clientAddress string, buf []byte) ([]byte, erro
 	return nil, errors.New("unsupported query")
 }
 
+// VerifiesAddress implements common.AddressVerifier, as challenge responses
+// are never larger than the request and queries require a challenge.
+func (q *QueryResponder) VerifiesAddress(req []byte) bool {
+	return true
+}
+
+// Match returns true if buf is a SQP challenge or query request.
+func Match(buf []byte) bool {
+	return isChallenge(buf) || isQuery(buf)
+}
+
 // isChallenge determines if the input buffer corresponds to a challenge packet.
 func isChallenge(buf []byte) bool {
-	return bytes.Equal(buf[0:5], []byte{0, 0, 0, 0, 0})
+	return len(buf) >= 5 && bytes.Equal(buf[0:5], []byte{0, 0, 0, 0, 0})
 }
 
 // isQuery determines if the input buffer corresponds to a query packet.
 func isQuery(buf []byte) bool {
-	return buf[0] == 1
+	return len(buf) > 0 && buf[0] == 1
 }
 
 // handleChallenge handles an incoming challenge packet.
 func (q *QueryResponder) handleChallenge(clientAddress string) ([]byte, error) {
 	v := rand.Uint32()
-	q.challenges.Store(clientAddress, v)
+	q.challenges.store(clientAddress, v)
 
 	resp := bytes.NewBuffer(nil)
 	err := common.WireWrite(
@@ -93,8 +193,8 @@ func (q *QueryResponder) handleChallenge(clientAddress string) ([]byte, error) {
 }
 
 // handleQuery handles an incoming query packet.
-func (q *QueryResponder) handleQuery(clientAddress string, buf []byte) ([]byte, error) {
-	expectedChallenge, ok := q.challenges.LoadAndDelete(clientAddress)
+func (q *QueryResponder) handleQuery(clientAddress string, buf []byte) ([][]byte, error) {
+	expectedChallenge, ok := q.challenges.take(clientAddress)
 	if !ok {
 		return nil, errors.New("no challenge")
 	}
@@ -104,7 +204,7 @@ func (q *QueryResponder) handleQuery(clientAddress string, buf []byte) ([]byte,
 	}
 
 	// Challenge doesn't match, return with no response
-	if binary.BigEndian.Uint32(buf[1:5]) != expectedChallenge.(uint32) {
+	if binary.BigEndian.Uint32(buf[1:5]) != expectedChallenge {
 		return nil, errors.New("challenge mismatch")
 	}
 
@@ -112,35 +212,118 @@ func (q *QueryResponder) handleQuery(clientAddress string, buf []byte) ([]byte,
 		return nil, fmt.Errorf("unsupported sqp version: %d", buf[6])
 	}
 
-	requestedChunks := buf[7]
+	payload, err := q.payload(buf[7])
+	if err != nil {
+		return nil, err
+	}
+
+	return q.packets(expectedChallenge, payload)
+}
+
+// payload returns the encoded payload containing the requested chunks.
+func (q *QueryResponder) payload(requestedChunks byte) ([]byte, error) {
 	wantsServerInfo := requestedChunks&0x1 == 1
+	wantsServerRules := requestedChunks&0x2 == 2
+	wantsPlayerInfo := requestedChunks&0x4 == 4
+	wantsTeamInfo := requestedChunks&0x8 == 8
 	wantsMetrics := requestedChunks&0x10 == 16
 
-	f := queryWireFormat{
-		Header:     1,
-		Challenge:  expectedChallenge.(uint32),
-		SQPVersion: 1,
-	}
-
-	resp := bytes.NewBuffer(nil)
+	f := queryPayloadWireFormat{}
+	state := q.state.State()
 
 	if wantsServerInfo {
-		f.ServerInfo = ServerInfoFromQueryState(q.state)
+		if err := validateServerInfo(state); err != nil {
+			return nil, err
+		}
+		f.ServerInfo = ServerInfoFromQueryState(state)
 		size := f.ServerInfo.Size()
 		f.ServerInfoLength = &size
-		f.PayloadLength += uint16(*f.ServerInfoLength) + 4
+	}
+
+	if wantsServerRules {
+		f.ServerRules = ServerRulesFromQueryState(state)
+		size := f.ServerRules.Size()
+		f.ServerRulesLength = &size
+	}
+
+	if wantsPlayerInfo {
+		var err error
+		if f.PlayerInfo, err = PlayerInfoFromQueryState(state); err != nil {
+			return nil, err
+		}
+		size := f.PlayerInfo.Size()
+		f.PlayerInfoLength = &size
+	}
+
+	if wantsTeamInfo {
+		var err error
+		if f.TeamInfo, err = TeamInfoFromQueryState(state); err != nil {
+			return nil, err
+		}
+		size := f.TeamInfo.Size()
+		f.TeamInfoLength = &size
 	}
 
 	if wantsMetrics {
-		f.Metrics = MetricsFromQueryState(q.state)
+		if len(state.Metrics) > MaxMetrics {
+			return nil, fmt.Errorf("metric count %d greater than %d", len(state.Metrics), MaxMetrics)
+		}
+		f.Metrics = MetricsFromQueryState(state)
 		size := f.Metrics.Size()
 		f.MetricsLength = &size
-		f.PayloadLength += uint16(*f.MetricsLength) + 4
 	}
 
+	resp := bytes.NewBuffer(nil)
 	if err := common.WireWrite(resp, q.enc, f); err != nil {
 		return nil, err
 	}
 
 	return resp.Bytes(), nil
 }
+
+// validateServerInfo returns an error if the server info of state can't be encoded.
+func validateServerInfo(state common.QueryState) error {
+	for name, v := range map[string]int32{"current players": state.CurrentPlayers, "max players": state.MaxPlayers} {
+		if v < 0 || v > math.MaxUint16 {
+			return fmt.Errorf("invalid %s %d", name, v)
+		}
+	}
+	return nil
+}
+
+// packets splits payload into numbered packets no larger than the max packet size.
+func (q *QueryResponder) packets(challenge uint32, payload []byte) ([][]byte, error) {
+	maxPayload := q.maxPacketSize - queryHeaderLength
+	count := (len(payload) + maxPayload - 1) / maxPayload
+	if count == 0 {
+		count = 1
+	} else if count > maxPackets {
+		return nil, fmt.Errorf("response too large (len: %d) requires %d packets, max %d", len(payload), count, maxPackets)
+	}
+
+	pkts := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		chunk := payload
+		if len(chunk) > maxPayload {
+			chunk = chunk[:maxPayload]
+		}
+		payload = payload[len(chunk):]
+
+		resp := bytes.NewBuffer(make([]byte, 0, queryHeaderLength+len(chunk)))
+		err := common.WireWrite(resp, q.enc, queryHeaderWireFormat{
+			Header:           1,
+			Challenge:        challenge,
+			SQPVersion:       1,
+			CurrentPacketNum: byte(i),
+			LastPacketNum:    byte(count - 1),
+			PayloadLength:    uint16(len(chunk)),
+		})
+		if err != nil {
+			return nil, err
+		}
+		resp.Write(chunk)
+		pkts = append(pkts, resp.Bytes())
+	}
+
+	return pkts, nil
+}
@@ -3,6 +3,7 @@ package sqp
 import (
 	"bytes"
 	"encoding/binary"
+	"strings"
 	"testing"
 
 	"github.com/multiplay/go-svrquery/lib/svrsample/common"
@@ -141,3 +142,140 @@ func TestSQPServer(t *testing.T) {
 		})
 	}
 }
+
+func TestSQPServerStateProvider(t *testing.T) {
+	addr := "client-addr:65534"
+	state := common.NewSyncState(common.QueryState{CurrentPlayers: 1, MaxPlayers: 2})
+	q, err := NewQueryResponderWithProvider(state)
+	require.NoError(t, err)
+
+	query := func() uint16 {
+		resp, err := q.Respond(addr, []byte{0, 0, 0, 0, 0})
+		require.NoError(t, err)
+
+		resp, err = q.Respond(addr, bytes.Join([][]byte{{1}, resp[1:5], {0, 1}, {0x1}}, nil))
+		require.NoError(t, err)
+		require.Greater(t, len(resp), 17)
+		return binary.BigEndian.Uint16(resp[15:17]) // current players
+	}
+
+	require.Equal(t, uint16(1), query())
+
+	state.UpdateState(common.QueryState{CurrentPlayers: 2, MaxPlayers: 2})
+	require.Equal(t, uint16(2), query())
+
+	state.Update(func(qs *common.QueryState) {
+		qs.CurrentPlayers--
+	})
+	require.Equal(t, uint16(1), query())
+}
+
+func TestSQPServerInvalidRecords(t *testing.T) {
+	addr := "client-addr:65534"
+	q, err := NewQueryResponder(common.QueryState{
+		Players: []common.Record{
+			{"name": common.NewString("alice")},
+			{"name": common.NewUint16(1)},
+		},
+	})
+	require.NoError(t, err)
+
+	resp, err := q.Respond(addr, []byte{0, 0, 0, 0, 0})
+	require.NoError(t, err)
+
+	_, err = q.Respond(addr, bytes.Join([][]byte{{1}, resp[1:5], {0, 1}, {0x4}}, nil))
+	require.EqualError(t, err, `players: record 1 field "name" has type uint16 (expected string)`)
+}
+
+func TestSQPServerPacketSplitting(t *testing.T) {
+	addr := "client-addr:65534"
+	state := common.QueryState{CurrentPlayers: 200, MaxPlayers: 200}
+	for i := 0; i < 200; i++ {
+		state.Players = append(state.Players, common.Record{"name": common.NewString(strings.Repeat("x", 20))})
+	}
+
+	q, err := NewQueryResponder(state, WithMaxPacketSize(500))
+	require.NoError(t, err)
+
+	query := func() ([]byte, [][]byte, error) {
+		resp, err := q.Respond(addr, []byte{0, 0, 0, 0, 0})
+		require.NoError(t, err)
+		req := bytes.Join([][]byte{{1}, resp[1:5], {0, 1}, {0x5}}, nil)
+		pkts, err := q.RespondPackets(addr, req)
+		return req, pkts, err
+	}
+
+	req, pkts, err := query()
+	require.NoError(t, err)
+	require.Len(t, pkts, 9)
+
+	var payload int
+	for i, p := range pkts {
+		require.LessOrEqual(t, len(p), 500)
+		require.Equal(t, req[1:5], p[1:5], "challenge")
+		require.Equal(t, byte(i), p[7], "current packet")
+		require.Equal(t, byte(len(pkts)-1), p[8], "last packet")
+		require.Equal(t, len(p)-queryHeaderLength, int(binary.BigEndian.Uint16(p[9:11])), "packet length")
+		payload += len(p) - queryHeaderLength
+	}
+	require.Equal(t, 4+10+4+2+1+6+200*21, payload)
+
+	// Respond can't return multiple packets.
+	resp, err := q.Respond(addr, []byte{0, 0, 0, 0, 0})
+	require.NoError(t, err)
+	_, err = q.Respond(addr, bytes.Join([][]byte{{1}, resp[1:5], {0, 1}, {0x5}}, nil))
+	require.EqualError(t, err, "response requires 9 packets")
+}
+
+func TestSQPServerLimits(t *testing.T) {
+	addr := "client-addr:65534"
+	testCases := []struct {
+		name   string
+		state  common.QueryState
+		chunks byte
+		err    string
+	}{
+		{
+			name:   "metrics",
+			state:  common.QueryState{Metrics: make([]float32, MaxMetrics+1)},
+			chunks: 0x10,
+			err:    "metric count 26 greater than 25",
+		},
+		{
+			name:   "players",
+			state:  common.QueryState{CurrentPlayers: 70000},
+			chunks: 0x1,
+			err:    "invalid current players 70000",
+		},
+		{
+			name:   "string",
+			state:  common.QueryState{ServerName: strings.Repeat("x", 256)},
+			chunks: 0x1,
+			err:    "string too long (len: 256)",
+		},
+		{
+			name: "packets",
+			state: common.QueryState{Rules: map[string]common.DynamicValue{
+				"a": common.NewString(strings.Repeat("x", 255)),
+				"b": common.NewString(strings.Repeat("x", 255)),
+			}},
+			chunks: 0x2,
+			err:    "response too large (len: 522) requires 261 packets, max 256",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			q, err := NewQueryResponder(tc.state, WithMaxPacketSize(queryHeaderLength+2))
+			require.NoError(t, err)
+
+			resp, err := q.Respond(addr, []byte{0, 0, 0, 0, 0})
+			require.NoError(t, err)
+			_, err = q.RespondPackets(addr, bytes.Join([][]byte{{1}, resp[1:5], {0, 1}, {tc.chunks}}, nil))
+			require.EqualError(t, err, tc.err)
+		})
+	}
+
+	_, err := NewQueryResponder(common.QueryState{}, WithMaxPacketSize(queryHeaderLength))
+	require.Error(t, err)
+}
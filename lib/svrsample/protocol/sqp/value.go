@@ -0,0 +1,37 @@
+package sqp
+
+import (
+	"bytes"
+
+	"github.com/multiplay/go-svrquery/lib/svrsample/common"
+)
+
+// valueSize returns the number of bytes dv will use on the wire, excluding its type.
+func valueSize(dv common.DynamicValue) uint32 {
+	switch dv.Type {
+	case common.TypeByte:
+		return 1
+	case common.TypeUint16:
+		return 2
+	case common.TypeUint32, common.TypeFloat32:
+		return 4
+	case common.TypeUint64:
+		return 8
+	case common.TypeString:
+		s, _ := dv.Value.(string)
+		return uint32(len(s) + 1)
+	}
+	return 0
+}
+
+// writeValue writes dv to resp, excluding its type.
+func writeValue(resp *bytes.Buffer, w common.WireEncoder, dv common.DynamicValue) error {
+	if err := dv.Validate(); err != nil {
+		return err
+	}
+
+	if s, ok := dv.Value.(string); ok {
+		return w.WriteString(resp, s)
+	}
+	return w.Write(resp, dv.Value)
+}
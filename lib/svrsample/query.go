@@ -5,8 +5,10 @@ import (
 	"fmt"
 
 	"github.com/multiplay/go-svrquery/lib/svrsample/common"
+	"github.com/multiplay/go-svrquery/lib/svrsample/protocol"
 
-	"github.com/multiplay/go-svrquery/lib/svrsample/protocol/sqp"
+	// Register all known protocols
+	_ "github.com/multiplay/go-svrquery/lib/svrsample/protocol/all"
 )
 
 var (
@@ -16,9 +18,25 @@ var (
 
 // GetResponder gets the appropriate responder for the protocol provided
 func GetResponder(proto string, state common.QueryState) (common.QueryResponder, error) {
-	switch proto {
-	case "sqp":
-		return sqp.NewQueryResponder(state)
+	return GetProviderResponder(proto, common.NewSyncState(state))
+}
+
+// GetProviderResponder gets the appropriate responder for the protocol provided
+// which responds with the current state of provider.
+func GetProviderResponder(proto string, provider common.StateProvider) (common.QueryResponder, error) {
+	f, err := protocol.Get(proto)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrProtoNotSupported, proto)
+	}
+	return f(provider)
+}
+
+// GetMatcher gets the Matcher of the requests of the protocol provided,
+// which is used to register its responder with a Mux.
+func GetMatcher(proto string) (Matcher, error) {
+	m, err := protocol.GetMatcher(proto)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrProtoNotSupported, proto)
 	}
-	return nil, fmt.Errorf("%w: %s", ErrProtoNotSupported, proto)
+	return m, nil
 }
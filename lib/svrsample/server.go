@@ -0,0 +1,502 @@
+package svrsample
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/multiplay/go-svrquery/lib/svrsample/common"
+)
+
+const (
+	// DefaultNetwork is the default network of a Server, which listens
+	// on both IPv4 and IPv6 if the address has no host.
+	DefaultNetwork = "udp"
+
+	// DefaultBufferSize is the default size of the buffer used to read requests.
+	DefaultBufferSize = 1500
+
+	// DefaultConcurrency is the default number of requests a Server handles concurrently.
+	DefaultConcurrency = 1
+
+	// DefaultWriteTimeout is the default timeout for writing a response.
+	DefaultWriteTimeout = time.Second
+
+	// DefaultMaxAmplification is the default maximum size of a response as a
+	// multiple of the request size, see WithMaxAmplification.
+	DefaultMaxAmplification = 3
+
+	// minReadErrorDelay and maxReadErrorDelay bound the delay before reading
+	// again after a read error, which doubles for each consecutive error.
+	minReadErrorDelay = 5 * time.Millisecond
+	maxReadErrorDelay = time.Second
+)
+
+var (
+	// ErrServerClosed is returned by Serve and Listen after the Server has been shutdown.
+	ErrServerClosed = errors.New("server closed")
+)
+
+// Option represents a Server option.
+type Option func(*Server) error
+
+// ErrorHandler is called with the address of the client, nil if unknown,
+// and the error which occurred handling a request.
+type ErrorHandler func(addr net.Addr, err error)
+
+// Server serves query requests using a common.QueryResponder.
+type Server struct {
+	addr         string
+	network      string
+	responder    common.QueryResponder
+	bufferSize   int
+	concurrency  int
+	writeTimeout time.Duration
+	errorHandler ErrorHandler
+	allow        []string
+	deny         []string
+	filter       *common.IPFilter
+	limiter      *common.RateLimiter
+	amplify      float64
+	faults       *Faults
+	faulty       *FaultResponder
+	stats        stats
+
+	mtx       sync.Mutex
+	conn      net.PacketConn
+	wrongConn net.PacketConn
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// WithNetwork sets the network the server listens on e.g. udp4 to only listen on IPv4.
+func WithNetwork(network string) Option {
+	return func(s *Server) error {
+		switch network {
+		case "udp", "udp4", "udp6":
+			s.network = network
+			return nil
+		}
+		return fmt.Errorf("unsupported network %q", network)
+	}
+}
+
+// WithBufferSize sets the size of the buffer used to read requests,
+// larger requests are truncated.
+func WithBufferSize(n int) Option {
+	return func(s *Server) error {
+		if n < 1 {
+			return fmt.Errorf("invalid buffer size %d", n)
+		}
+		s.bufferSize = n
+		return nil
+	}
+}
+
+// WithConcurrency sets the number of requests the server handles concurrently.
+func WithConcurrency(n int) Option {
+	return func(s *Server) error {
+		if n < 1 {
+			return fmt.Errorf("invalid concurrency %d", n)
+		}
+		s.concurrency = n
+		return nil
+	}
+}
+
+// WithWriteTimeout sets the timeout for writing a response.
+func WithWriteTimeout(t time.Duration) Option {
+	return func(s *Server) error {
+		s.writeTimeout = t
+		return nil
+	}
+}
+
+// WithErrorHandler sets the handler called for errors which occur handling requests.
+func WithErrorHandler(h ErrorHandler) Option {
+	return func(s *Server) error {
+		s.errorHandler = h
+		return nil
+	}
+}
+
+// WithAllow only allows requests from the given CIDRs or IP addresses.
+func WithAllow(cidrs ...string) Option {
+	return func(s *Server) error {
+		s.allow = append(s.allow, cidrs...)
+		return nil
+	}
+}
+
+// WithDeny denies requests from the given CIDRs or IP addresses.
+func WithDeny(cidrs ...string) Option {
+	return func(s *Server) error {
+		s.deny = append(s.deny, cidrs...)
+		return nil
+	}
+}
+
+// WithRateLimit limits the requests from each IP address to rate per second
+// with bursts of up to burst requests.
+func WithRateLimit(rate float64, burst int) Option {
+	return func(s *Server) error {
+		if rate <= 0 {
+			return fmt.Errorf("invalid rate %v", rate)
+		}
+		s.limiter = common.NewRateLimiter(rate, burst)
+		return nil
+	}
+}
+
+// WithMaxAmplification drops responses larger than factor times the size of
+// the request, preventing the server being used for amplification attacks.
+// Responders which implement common.AddressVerifier, such as SQP, which only
+// send large responses once the client has proven its address with a
+// challenge, aren't limited. A factor of zero disables the limit.
+func WithMaxAmplification(factor float64) Option {
+	return func(s *Server) error {
+		if factor < 0 {
+			return fmt.Errorf("invalid amplification factor %v", factor)
+		}
+		s.amplify = factor
+		return nil
+	}
+}
+
+// WithFaults injects faults into the responses of the server, which is used
+// to test how clients handle misbehaving servers, see Faults.
+func WithFaults(faults Faults) Option {
+	return func(s *Server) error {
+		if err := faults.Validate(); err != nil {
+			return err
+		}
+		s.faults = &faults
+		return nil
+	}
+}
+
+// NewServer creates a new server which responds to requests on addr using responder.
+func NewServer(addr string, responder common.QueryResponder, options ...Option) (*Server, error) {
+	s := &Server{
+		addr:         addr,
+		network:      DefaultNetwork,
+		responder:    responder,
+		bufferSize:   DefaultBufferSize,
+		concurrency:  DefaultConcurrency,
+		writeTimeout: DefaultWriteTimeout,
+		amplify:      DefaultMaxAmplification,
+		errorHandler: func(net.Addr, error) {},
+	}
+
+	for _, o := range options {
+		if err := o(s); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(s.allow) > 0 || len(s.deny) > 0 {
+		var err error
+		if s.filter, err = common.NewIPFilter(s.allow, s.deny); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.faults != nil {
+		var err error
+		if s.faulty, err = NewFaultResponder(s.responder, *s.faults); err != nil {
+			return nil, err
+		}
+		s.responder = s.faulty
+	}
+
+	return s, nil
+}
+
+// Stats returns the counters of the packets handled by the server.
+func (s *Server) Stats() Stats {
+	return Stats{
+		Received:      s.stats.received.Load(),
+		Responded:     s.stats.responded.Load(),
+		Denied:        s.stats.denied.Load(),
+		Limited:       s.stats.limited.Load(),
+		Amplification: s.stats.amplification.Load(),
+		Dropped:       s.stats.dropped.Load(),
+		Errors:        s.stats.errors.Load(),
+	}
+}
+
+// Listen starts listening on the address of the server if it isn't already.
+// It only needs to be called before Serve if Addr is required first.
+func (s *Server) Listen() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	switch {
+	case s.closed:
+		return ErrServerClosed
+	case s.conn != nil:
+		return nil
+	}
+
+	conn, err := net.ListenPacket(s.network, s.addr)
+	if err != nil {
+		return err
+	}
+
+	if s.faulty != nil && s.faulty.faults.WrongPort > 0 {
+		if s.wrongConn, err = listenWrongPort(s.network, s.addr); err != nil {
+			conn.Close() // nolint: errcheck
+			return err
+		}
+	}
+
+	s.conn = conn
+	return nil
+}
+
+// listenWrongPort listens on a random port of the host of addr, which is
+// used to send responses from the wrong port.
+func listenWrongPort(network, addr string) (net.PacketConn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.ListenPacket(network, net.JoinHostPort(host, "0"))
+	if err != nil {
+		return nil, fmt.Errorf("wrong port: %w", err)
+	}
+	return conn, nil
+}
+
+// Addr returns the address the server is listening on, nil if it isn't.
+func (s *Server) Addr() net.Addr {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.LocalAddr()
+}
+
+// Serve listens, if not already, and handles requests until ctx is done or
+// the server is shutdown, in which case nil is returned.
+func (s *Server) Serve(ctx context.Context) error {
+	if err := s.Listen(); err != nil {
+		return err
+	}
+
+	s.mtx.Lock()
+	if s.closed {
+		s.mtx.Unlock()
+		return nil
+	}
+	conn := s.conn
+	s.wg.Add(s.concurrency)
+	s.mtx.Unlock()
+
+	for i := 0; i < s.concurrency; i++ {
+		go s.handle(conn)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return s.Shutdown(context.Background())
+	case <-done:
+		return nil
+	}
+}
+
+// Shutdown stops the server and waits for requests being handled to
+// complete or ctx to be done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mtx.Lock()
+	if s.closed {
+		s.mtx.Unlock()
+		return nil
+	}
+	s.closed = true
+	var err error
+	if s.conn != nil {
+		err = s.conn.Close()
+	}
+	if s.wrongConn != nil {
+		s.wrongConn.Close() // nolint: errcheck
+	}
+	s.mtx.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-done:
+		return err
+	}
+}
+
+// handle reads and responds to requests until conn is closed.
+func (s *Server) handle(conn net.PacketConn) {
+	defer s.wg.Done()
+
+	buf := make([]byte, s.bufferSize)
+	var delay time.Duration
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		} else if err != nil {
+			s.errorHandler(addr, fmt.Errorf("read: %w", err))
+
+			// Back off so persistent errors don't spin.
+			if delay *= 2; delay == 0 {
+				delay = minReadErrorDelay
+			} else if delay > maxReadErrorDelay {
+				delay = maxReadErrorDelay
+			}
+			time.Sleep(delay)
+			continue
+		}
+		delay = 0
+
+		s.stats.received.Add(1)
+		if !s.accept(addr) {
+			continue
+		}
+
+		pkts, err := s.respond(addr.String(), buf[:n])
+		if errors.Is(err, ErrNoMatch) {
+			// Not a request of a served protocol, which is expected noise on a public port.
+			s.stats.denied.Add(1)
+			continue
+		} else if err != nil {
+			s.stats.errors.Add(1)
+			s.errorHandler(addr, fmt.Errorf("respond: %w", err))
+			continue
+		} else if len(pkts) == 0 {
+			// Dropped by injected faults.
+			s.stats.dropped.Add(1)
+			continue
+		} else if !s.withinAmplification(buf[:n], pkts) {
+			s.stats.amplification.Add(1)
+			continue
+		}
+
+		s.stats.responded.Add(1)
+		if s.faulty == nil {
+			s.write(conn, addr, pkts)
+			continue
+		}
+
+		w := conn
+		if s.wrongConn != nil && s.faulty.wrongPort() {
+			w = s.wrongConn
+		}
+
+		if d := s.faulty.delay(); d > 0 {
+			// Delay without blocking other requests.
+			s.wg.Add(1)
+			time.AfterFunc(d, func() {
+				defer s.wg.Done()
+				s.write(w, addr, pkts)
+			})
+			continue
+		}
+		s.write(w, addr, pkts)
+	}
+}
+
+// write writes the response packets pkts to addr using conn.
+func (s *Server) write(conn net.PacketConn, addr net.Addr, pkts [][]byte) {
+	for _, resp := range pkts {
+		if len(resp) == 0 {
+			continue
+		}
+
+		if err := conn.SetWriteDeadline(time.Now().Add(s.writeTimeout)); err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				s.errorHandler(addr, fmt.Errorf("set write deadline: %w", err))
+			}
+			return
+		}
+
+		if _, err := conn.WriteTo(resp, addr); err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				s.errorHandler(addr, fmt.Errorf("write: %w", err))
+			}
+			return
+		}
+	}
+}
+
+// accept returns true if a request from addr is allowed by the IP filter
+// and rate limit, if any, updating the stats if not.
+func (s *Server) accept(addr net.Addr) bool {
+	if s.filter == nil && s.limiter == nil {
+		return true
+	}
+
+	var ip net.IP
+	if ua, ok := addr.(*net.UDPAddr); ok {
+		ip = ua.IP
+	}
+
+	switch {
+	case s.filter != nil && !s.filter.Allowed(ip):
+		s.stats.denied.Add(1)
+		return false
+	case s.limiter != nil && !s.limiter.Allow(ip.String()):
+		s.stats.limited.Add(1)
+		return false
+	}
+	return true
+}
+
+// withinAmplification returns true if the total size of pkts is within the
+// amplification limit for the request req, if any.
+func (s *Server) withinAmplification(req []byte, pkts [][]byte) bool {
+	if s.amplify == 0 {
+		return true
+	} else if v, ok := s.responder.(common.AddressVerifier); ok && v.VerifiesAddress(req) {
+		return true
+	}
+
+	var size int
+	for _, p := range pkts {
+		size += len(p)
+	}
+	return float64(size) <= s.amplify*float64(len(req))
+}
+
+// respond returns the response packets to the request in buf.
+func (s *Server) respond(addr string, buf []byte) ([][]byte, error) {
+	return respondPackets(s.responder, addr, buf)
+}
+
+// respondPackets returns the response packets of responder to the request
+// in buf, using common.MultiPacketResponder if the responder supports it.
+func respondPackets(responder common.QueryResponder, addr string, buf []byte) ([][]byte, error) {
+	if m, ok := responder.(common.MultiPacketResponder); ok {
+		return m.RespondPackets(addr, buf)
+	}
+
+	resp, err := responder.Respond(addr, buf)
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{resp}, nil
+}
@@ -0,0 +1,370 @@
+package svrsample
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/multiplay/go-svrquery/lib/svrquery"
+	sqpclient "github.com/multiplay/go-svrquery/lib/svrquery/protocol/sqp"
+	"github.com/multiplay/go-svrquery/lib/svrsample/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// errorRecorder records the errors passed to its handler.
+type errorRecorder struct {
+	mtx  sync.Mutex
+	errs []error
+}
+
+func (r *errorRecorder) handle(addr net.Addr, err error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.errs = append(r.errs, err)
+}
+
+func (r *errorRecorder) len() int {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return len(r.errs)
+}
+
+func newTestServer(t *testing.T, network, addr string, options ...Option) (*Server, <-chan error) {
+	t.Helper()
+	r, err := GetResponder("sqp", common.QueryState{CurrentPlayers: 1, MaxPlayers: 2})
+	require.NoError(t, err)
+
+	options = append([]Option{WithNetwork(network)}, options...)
+	s, err := NewServer(addr, r, options...)
+	require.NoError(t, err)
+	require.NoError(t, s.Listen())
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- s.Serve(context.Background())
+	}()
+	t.Cleanup(func() { s.Shutdown(context.Background()) }) // nolint: errcheck
+
+	return s, errc
+}
+
+func TestServer(t *testing.T) {
+	errs := &errorRecorder{}
+	s, errc := newTestServer(t, "udp4", "127.0.0.1:0", WithConcurrency(4), WithErrorHandler(errs.handle))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c, err := svrquery.NewClient("sqp", s.Addr().String())
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer c.Close()
+
+			r, err := c.Query()
+			if assert.NoError(t, err) {
+				assert.Equal(t, int64(1), r.NumClients())
+			}
+		}()
+	}
+	wg.Wait()
+	require.Zero(t, errs.len())
+
+	// Invalid requests are reported to the error handler.
+	conn, err := net.Dial("udp", s.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte{0xFF})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return errs.len() == 1 }, time.Second, time.Millisecond*10)
+
+	require.NoError(t, s.Shutdown(context.Background()))
+	require.NoError(t, <-errc)
+	require.ErrorIs(t, s.Listen(), ErrServerClosed)
+}
+
+func TestServerDualStack(t *testing.T) {
+	ln, err := net.ListenPacket("udp6", "[::1]:0")
+	if err != nil {
+		t.Skip("IPv6 not available")
+	}
+	ln.Close()
+
+	s, _ := newTestServer(t, DefaultNetwork, ":0")
+	port := s.Addr().(*net.UDPAddr).Port
+	for _, host := range []string{"127.0.0.1", "::1"} {
+		c, err := svrquery.NewClient("sqp", net.JoinHostPort(host, strconv.Itoa(port)))
+		require.NoError(t, err)
+		_, err = c.Query()
+		c.Close()
+		require.NoError(t, err, host)
+	}
+}
+
+func TestServerServeContext(t *testing.T) {
+	r, err := GetResponder("sqp", common.QueryState{})
+	require.NoError(t, err)
+	s, err := NewServer("127.0.0.1:0", r, WithBufferSize(64))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		errc <- s.Serve(ctx)
+	}()
+	require.Eventually(t, func() bool { return s.Addr() != nil }, time.Second, time.Millisecond*10)
+
+	cancel()
+	require.NoError(t, <-errc)
+}
+
+func TestNewServerOptions(t *testing.T) {
+	for _, o := range []Option{WithNetwork("tcp"), WithBufferSize(0), WithConcurrency(0)} {
+		_, err := NewServer(":0", nil, o)
+		require.Error(t, err)
+	}
+}
+
+func TestServerRoundTrip(t *testing.T) {
+	state := common.QueryState{
+		CurrentPlayers: 2,
+		MaxPlayers:     8,
+		ServerName:     "Name",
+		GameType:       "Game Type",
+		BuildID:        "1.2.3",
+		Map:            "Map",
+		Port:           7777,
+		Rules: map[string]common.DynamicValue{
+			"byte":    common.NewByte(1),
+			"uint16":  common.NewUint16(2),
+			"uint32":  common.NewUint32(3),
+			"uint64":  common.NewUint64(4),
+			"string":  common.NewString("five"),
+			"float32": common.NewFloat32(6.5),
+		},
+		Players: []common.Record{
+			{"name": common.NewString("alice"), "score": common.NewUint32(10)},
+			{"name": common.NewString("bob"), "score": common.NewUint32(20)},
+		},
+		Teams: []common.Record{
+			{"name": common.NewString("red"), "size": common.NewByte(2)},
+		},
+		Metrics: []float32{1.5},
+	}
+
+	responder, err := GetResponder("sqp", state)
+	require.NoError(t, err)
+	s, err := NewServer("127.0.0.1:0", responder)
+	require.NoError(t, err)
+	require.NoError(t, s.Listen())
+	go s.Serve(context.Background())       // nolint: errcheck
+	defer s.Shutdown(context.Background()) // nolint: errcheck
+
+	c, err := svrquery.NewClient("sqp", s.Addr().String(), svrquery.WithArg(sqpclient.ChunksArg, "info+rules+players+teams+metrics"))
+	require.NoError(t, err)
+	defer c.Close()
+
+	resp, err := c.Query()
+	require.NoError(t, err)
+	qr := resp.(*sqpclient.QueryResponse)
+
+	require.Equal(t, &sqpclient.ServerInfoChunk{
+		ChunkLength:    qr.ServerInfo.ChunkLength,
+		CurrentPlayers: 2,
+		MaxPlayers:     8,
+		ServerName:     "Name",
+		GameType:       "Game Type",
+		BuildID:        "1.2.3",
+		Map:            "Map",
+		Port:           7777,
+	}, qr.ServerInfo)
+
+	require.Len(t, qr.ServerRules.Rules, len(state.Rules))
+	for k, v := range state.Rules {
+		require.Equal(t, sqpclient.DataType(v.Type), qr.ServerRules.Rules[k].Type, k)
+		require.Equal(t, v.Value, qr.ServerRules.Rules[k].Value, k)
+	}
+
+	require.Len(t, qr.PlayerInfo.Players, 2)
+	require.Equal(t, "bob", qr.PlayerInfo.Players[1]["name"].String())
+	require.Equal(t, uint32(20), qr.PlayerInfo.Players[1]["score"].Uint32())
+
+	require.Len(t, qr.TeamInfo.Teams, 1)
+	require.Equal(t, "red", qr.TeamInfo.Teams[0]["name"].String())
+	require.Equal(t, byte(2), qr.TeamInfo.Teams[0]["size"].Byte())
+
+	require.Equal(t, []float32{1.5}, qr.Metrics.Metrics)
+}
+
+func TestServerRoundTripMultiPacket(t *testing.T) {
+	state := common.QueryState{CurrentPlayers: 150, MaxPlayers: 200}
+	for i := 0; i < 150; i++ {
+		state.Players = append(state.Players, common.Record{
+			"name":  common.NewString(strings.Repeat("p", 24)),
+			"score": common.NewUint32(uint32(i)),
+		})
+	}
+
+	responder, err := GetResponder("sqp", state)
+	require.NoError(t, err)
+	s, err := NewServer("127.0.0.1:0", responder)
+	require.NoError(t, err)
+	require.NoError(t, s.Listen())
+	go s.Serve(context.Background())       // nolint: errcheck
+	defer s.Shutdown(context.Background()) // nolint: errcheck
+
+	c, err := svrquery.NewClient("sqp", s.Addr().String(), svrquery.WithArg(sqpclient.ChunksArg, "info+players"))
+	require.NoError(t, err)
+	defer c.Close()
+
+	resp, err := c.Query()
+	require.NoError(t, err)
+
+	qr := resp.(*sqpclient.QueryResponse)
+	require.Len(t, qr.PlayerInfo.Players, 150)
+	require.Equal(t, uint32(149), qr.PlayerInfo.Players[149]["score"].Uint32())
+}
+
+func TestServerControls(t *testing.T) {
+	testCases := []struct {
+		name     string
+		options  []Option
+		expected Stats
+	}{
+		{
+			name:     "deny",
+			options:  []Option{WithDeny("127.0.0.0/8")},
+			expected: Stats{Received: 2, Denied: 2},
+		},
+		{
+			name:     "allow",
+			options:  []Option{WithAllow("10.0.0.0/8")},
+			expected: Stats{Received: 2, Denied: 2},
+		},
+		{
+			name:     "rate-limit",
+			options:  []Option{WithRateLimit(0.001, 1)},
+			expected: Stats{Received: 2, Responded: 1, Limited: 1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, _ := newTestServer(t, "udp4", "127.0.0.1:0", tc.options...)
+
+			c, err := svrquery.NewClient("sqp", s.Addr().String(), svrquery.WithTimeout(time.Millisecond*100))
+			require.NoError(t, err)
+			defer c.Close()
+
+			// Challenge, and query if the challenge succeeds.
+			_, err = c.Query()
+			require.Error(t, err)
+			if tc.expected.Responded == 0 {
+				// Retry as only the challenge is sent.
+				_, err = c.Query()
+				require.Error(t, err)
+			}
+
+			require.Eventually(t, func() bool {
+				return s.Stats() == tc.expected
+			}, time.Second, time.Millisecond*10, "%+v", s.Stats())
+		})
+	}
+
+	_, err := NewServer(":0", nil, WithAllow("invalid"))
+	require.Error(t, err)
+}
+
+func TestServerAmplification(t *testing.T) {
+	r, err := GetResponder("sqp", common.QueryState{CurrentPlayers: 1, MaxPlayers: 2, ServerName: "amplified"})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name      string
+		responder common.QueryResponder
+		options   []Option
+		expected  Stats
+	}{
+		{
+			name:      "verified",
+			responder: r,
+			expected:  Stats{Received: 2, Responded: 2},
+		},
+		{
+			// Hide common.AddressVerifier so the query response is limited.
+			name:      "unverified",
+			responder: struct{ common.QueryResponder }{r},
+			expected:  Stats{Received: 2, Responded: 1, Amplification: 1},
+		},
+		{
+			name:      "unverified-disabled",
+			responder: struct{ common.QueryResponder }{r},
+			options:   []Option{WithMaxAmplification(0)},
+			expected:  Stats{Received: 2, Responded: 2},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := NewServer("127.0.0.1:0", tc.responder, tc.options...)
+			require.NoError(t, err)
+			require.NoError(t, s.Listen())
+			go s.Serve(context.Background())                       // nolint: errcheck
+			t.Cleanup(func() { s.Shutdown(context.Background()) }) // nolint: errcheck
+
+			c, err := svrquery.NewClient("sqp", s.Addr().String(), svrquery.WithTimeout(time.Millisecond*100))
+			require.NoError(t, err)
+			defer c.Close()
+
+			_, err = c.Query()
+			require.Equal(t, tc.expected.Amplification == 0, err == nil, "%v", err)
+			require.Eventually(t, func() bool {
+				return s.Stats() == tc.expected
+			}, time.Second, time.Millisecond*10, "%+v", s.Stats())
+		})
+	}
+
+	_, err = NewServer(":0", nil, WithMaxAmplification(-1))
+	require.Error(t, err)
+}
+
+// failingConn is a net.PacketConn whose reads fail until it's closed.
+type failingConn struct {
+	net.PacketConn
+	closed chan struct{}
+}
+
+func (c *failingConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	select {
+	case <-c.closed:
+		return 0, nil, net.ErrClosed
+	default:
+		return 0, nil, errors.New("read failed")
+	}
+}
+
+func TestServerReadErrorBackoff(t *testing.T) {
+	var errs errorRecorder
+	s, err := NewServer("127.0.0.1:0", nil, WithErrorHandler(errs.handle))
+	require.NoError(t, err)
+
+	conn := &failingConn{closed: make(chan struct{})}
+	s.wg.Add(1)
+	go s.handle(conn)
+
+	time.Sleep(time.Millisecond * 100)
+	close(conn.closed)
+	s.wg.Wait()
+
+	// Delays of 5, 10, 20, 40 and 80ms.
+	require.Positive(t, errs.len())
+	require.LessOrEqual(t, errs.len(), 6)
+}
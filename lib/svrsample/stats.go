@@ -0,0 +1,60 @@
+package svrsample
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Stats are the counters of the packets handled by a Server.
+type Stats struct {
+	// Received is the number of packets received.
+	Received uint64 `json:"received"`
+
+	// Responded is the number of requests responded to.
+	Responded uint64 `json:"responded"`
+
+	// Denied is the number of packets dropped as their source isn't allowed
+	// or, when serving a Mux, no responder matches them.
+	Denied uint64 `json:"denied"`
+
+	// Limited is the number of packets dropped by rate limiting.
+	Limited uint64 `json:"limited"`
+
+	// Amplification is the number of requests dropped as their response would
+	// exceed the amplification limit.
+	Amplification uint64 `json:"amplification"`
+
+	// Dropped is the number of responses dropped by injected faults.
+	Dropped uint64 `json:"dropped"`
+
+	// Errors is the number of requests the responder failed to respond to.
+	Errors uint64 `json:"errors"`
+}
+
+// Add adds the counters of o to s.
+func (s *Stats) Add(o Stats) {
+	s.Received += o.Received
+	s.Responded += o.Responded
+	s.Denied += o.Denied
+	s.Limited += o.Limited
+	s.Amplification += o.Amplification
+	s.Dropped += o.Dropped
+	s.Errors += o.Errors
+}
+
+// String implements fmt.Stringer.
+func (s Stats) String() string {
+	return fmt.Sprintf("%d packets: %d responded, %d denied, %d limited, %d over the amplification limit, %d dropped, %d errors",
+		s.Received, s.Responded, s.Denied, s.Limited, s.Amplification, s.Dropped, s.Errors)
+}
+
+// stats are the live counters of a Server.
+type stats struct {
+	received      atomic.Uint64
+	responded     atomic.Uint64
+	denied        atomic.Uint64
+	limited       atomic.Uint64
+	amplification atomic.Uint64
+	dropped       atomic.Uint64
+	errors        atomic.Uint64
+}
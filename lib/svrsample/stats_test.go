@@ -0,0 +1,14 @@
+package svrsample
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	st := Stats{Received: 1, Responded: 1}
+	st.Add(Stats{Received: 10, Responded: 2, Denied: 3, Limited: 1, Amplification: 1, Dropped: 2, Errors: 1})
+	require.Equal(t, Stats{Received: 11, Responded: 3, Denied: 3, Limited: 1, Amplification: 1, Dropped: 2, Errors: 1}, st)
+	require.Equal(t, "11 packets: 3 responded, 3 denied, 1 limited, 1 over the amplification limit, 2 dropped, 1 errors", st.String())
+}
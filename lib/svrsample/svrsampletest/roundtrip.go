@@ -0,0 +1,95 @@
+// Package svrsampletest provides utilities for testing that sample responders
+// and the query clients of the same protocol agree.
+package svrsampletest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/multiplay/go-svrquery/lib/svrproxy"
+	"github.com/multiplay/go-svrquery/lib/svrquery"
+	queryprotocol "github.com/multiplay/go-svrquery/lib/svrquery/protocol"
+	"github.com/multiplay/go-svrquery/lib/svrquery/protocol/sqp"
+	"github.com/multiplay/go-svrquery/lib/svrsample"
+	"github.com/multiplay/go-svrquery/lib/svrsample/common"
+	"github.com/multiplay/go-svrquery/lib/svrsample/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	// clientOptions are the client options by protocol which request all of the state.
+	clientOptions = map[string][]svrquery.Option{
+		"sqp": {svrquery.WithArg(sqp.ChunksArg, "info+rules+players+teams+metrics")},
+	}
+)
+
+// Protocols returns the names, in order, of the protocols registered with
+// both the query and responder registries.
+func Protocols() []string {
+	var names []string
+	for _, name := range protocol.Names() {
+		if queryprotocol.Supported(name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// RoundTrip checks that state, served by the responder of proto, is returned
+// by a svrquery.Client using the same protocol. The whole state is compared,
+// using svrproxy.StateFromResponse to convert the response, so it must only
+// contain what the protocol can represent.
+func RoundTrip(t testing.TB, proto string, state common.QueryState) {
+	t.Helper()
+
+	responder, err := svrsample.GetResponder(proto, state)
+	require.NoError(t, err)
+
+	s, err := svrsample.NewServer("127.0.0.1:0", responder)
+	require.NoError(t, err)
+	require.NoError(t, s.Listen())
+	go s.Serve(context.Background())       // nolint: errcheck
+	defer s.Shutdown(context.Background()) // nolint: errcheck
+
+	c, err := svrquery.NewClient(proto, s.Addr().String(), clientOptions[proto]...)
+	require.NoError(t, err)
+	defer c.Close()
+
+	resp, err := c.Query()
+	require.NoError(t, err)
+	require.Equal(t, int64(state.CurrentPlayers), resp.NumClients(), "current players")
+	require.Equal(t, int64(state.MaxPlayers), resp.MaxClients(), "max players")
+	require.Equal(t, normalize(state), normalize(svrproxy.StateFromResponse(resp)))
+}
+
+// normalize returns qs with empty rules, players, teams and metrics as nil,
+// as protocols don't distinguish them.
+func normalize(qs common.QueryState) common.QueryState {
+	if len(qs.Rules) == 0 {
+		qs.Rules = nil
+	}
+	if len(qs.Players) == 0 {
+		qs.Players = nil
+	}
+	if len(qs.Teams) == 0 {
+		qs.Teams = nil
+	}
+	if len(qs.Metrics) == 0 {
+		qs.Metrics = nil
+	}
+	return qs
+}
+
+// RoundTripAll runs RoundTrip for every protocol registered on both sides,
+// failing if there are none.
+func RoundTripAll(t *testing.T, state common.QueryState) {
+	t.Helper()
+
+	names := Protocols()
+	require.NotEmpty(t, names, "no protocols registered with both registries")
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			RoundTrip(t, name, state)
+		})
+	}
+}
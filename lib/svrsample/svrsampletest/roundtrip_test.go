@@ -0,0 +1,34 @@
+package svrsampletest
+
+import (
+	"testing"
+
+	"github.com/multiplay/go-svrquery/lib/svrsample/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundTripAll(t *testing.T) {
+	require.Contains(t, Protocols(), "sqp")
+	RoundTripAll(t, common.QueryState{
+		CurrentPlayers: 3,
+		MaxPlayers:     16,
+		ServerName:     "Name",
+		GameType:       "Game Type",
+		BuildID:        "1.0.2",
+		Map:            "Map",
+		Port:           7777,
+		Rules: map[string]common.DynamicValue{
+			"mode":   common.NewString("ranked"),
+			"round":  common.NewUint16(3),
+			"rating": common.NewFloat32(1.5),
+		},
+		Players: []common.Record{
+			{"name": common.NewString("alice"), "score": common.NewUint32(10)},
+			{"name": common.NewString("bob"), "score": common.NewUint32(7)},
+		},
+		Teams: []common.Record{
+			{"name": common.NewString("red"), "score": common.NewUint64(100)},
+		},
+		Metrics: []float32{1.5, 60},
+	})
+}